@@ -24,9 +24,9 @@ package config
 import (
 	"errors"
 	"fmt"
-	"log"
 	"os"
 
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
 	"github.com/liuminhaw/wrestic-bkp/restic"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -68,21 +68,20 @@ var showCmd = &cobra.Command{
 			backupName = args[0]
 		}
 
-		err := restic.ResticCheck()
+		backups, err := restic.NewConfig(viper.ConfigFileUsed())
 		if err != nil {
-			fmt.Println("restic should be installed before running this program")
-			os.Exit(1)
+			logging.Fatal("check", "error", err)
 		}
 
-		backups, err := restic.NewConfig(viper.ConfigFileUsed())
-		if err != nil {
-			log.Fatalf("check: %v\n", err)
+		if err := restic.ResticCheck(backups.Binary()); err != nil {
+			fmt.Println("restic should be installed before running this program")
+			os.Exit(1)
 		}
 		// Now you can use the config struct, for example, print the backup names
 		for _, backup := range backups.Backups {
 			data, err := yaml.Marshal(backup)
 			if err != nil {
-				log.Fatalf("config check: %v\n", err)
+				logging.Fatal("config check", "error", err)
 			}
 			if backupName == "" || backupName == backup.Name {
 				fmt.Printf("--- config backup:\n%s\n\n", string(data))
@@ -0,0 +1,108 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package doctor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	conf "github.com/liuminhaw/wrestic-bkp/cmd/config"
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// DoctorCmd represents the doctor command
+var DoctorCmd = &cobra.Command{
+	Use:   "doctor [BackupName]",
+	Short: "Check restic installation and every configured backup",
+	Long: `Check restic binary and version, then for each backup (or only BackupName) check
+config, sources, destination, credentials and repository state.
+Exit with status 1 if any check failed`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.MaximumNArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return nil
+		}
+
+		config, err := restic.NewConfigForDiagnosis(viper.ConfigFileUsed())
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		if !config.IsValidName(args[0]) {
+			return fmt.Errorf("given name '%s' not found in config names: %v", args[0], conf.ValidConfigNames(config))
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var backupName string
+		if len(args) == 1 {
+			backupName = args[0]
+		}
+
+		// Invalid backups are reported as findings instead of failing to load
+		config, err := restic.NewConfigForDiagnosis(viper.ConfigFileUsed())
+		if err != nil {
+			logging.Fatal("doctor", "error", err)
+		}
+
+		fmt.Println("=== config")
+		findings := config.Diagnose()
+		printFindings(findings)
+		failed := restic.HasFailure(findings)
+
+		fmt.Println("\n=== restic")
+		findings = restic.DiagnoseRestic(cmd.Context(), config)
+		printFindings(findings)
+		if restic.HasFailure(findings) {
+			// Backup checks need working restic
+			os.Exit(1)
+		}
+
+		for _, backup := range config.Backups {
+			if backupName != "" && backupName != backup.Name {
+				continue
+			}
+
+			fmt.Printf("\n=== backup: %s (%s)\n", backup.Name, backup.Type)
+			findings := backup.Diagnose(cmd.Context())
+			printFindings(findings)
+			failed = failed || restic.HasFailure(findings)
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// doctorCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// doctorCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
+
+// printFindings prints each finding as a line with status, followed by
+// indented hint for warnings and failures
+func printFindings(findings []restic.Finding) {
+	for _, finding := range findings {
+		fmt.Printf("[%s] %s: %s\n", finding.Status, finding.Check, finding.Message)
+		if finding.Status != restic.StatusPass && finding.Hint != "" {
+			for _, line := range strings.Split(finding.Hint, "\n") {
+				fmt.Printf("       %s\n", line)
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package logging sets up the structured logger shared by wrestic-bkp commands
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const (
+	FormatText string = "text"
+	FormatJSON string = "json"
+
+	defaultMaxSizeMB  int = 10
+	defaultMaxBackups int = 3
+)
+
+// Options are logger settings from command line flags
+type Options struct {
+	// Level is one of debug, info, warn or error
+	Level string
+	// Format is FormatText or FormatJSON
+	Format string
+	// File is path of log file, logs go to stderr if empty
+	File string
+	// MaxSizeMB is size in megabytes at which File is rotated
+	MaxSizeMB int
+}
+
+// Setup creates logger from opts and sets it as slog default logger
+func Setup(opts Options) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+		return fmt.Errorf("logging setup: invalid level %s", opts.Level)
+	}
+
+	var w io.Writer = os.Stderr
+	if opts.File != "" {
+		maxSize := opts.MaxSizeMB
+		if maxSize <= 0 {
+			maxSize = defaultMaxSizeMB
+		}
+		file, err := newRotatingFile(opts.File, int64(maxSize)<<20, defaultMaxBackups)
+		if err != nil {
+			return fmt.Errorf("logging setup: %w", err)
+		}
+		w = file
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case FormatText:
+		handler = slog.NewTextHandler(w, handlerOpts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		return fmt.Errorf("logging setup: invalid format %s", opts.Format)
+	}
+	slog.SetDefault(slog.New(handler))
+
+	return nil
+}
+
+// Fatal logs msg with args at error level and exits with status 1
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
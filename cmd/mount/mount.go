@@ -0,0 +1,105 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package mount
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// MountCmd represents the mount command
+var MountCmd = &cobra.Command{
+	Use:   "mount MountName|BackupName [mountpoint]",
+	Short: "Mount repository as file system for browsing snapshots",
+	Long: `Mount repository of backup on mountpoint, with snapshots limited by paths,
+tags and hosts of MountName in mounts config, or all snapshots of BackupName.
+Mountpoint should be an empty directory, default to mountpoint of MountName.
+Stay in foreground until interrupted with Ctrl-C, then unmount`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.RangeArgs(1, 2)(cmd, args); err != nil {
+			return err
+		}
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		if _, err := config.ReadMount(args[0]); err != nil {
+			return fmt.Errorf("given name '%s' not found in mount names: %v or config names: %v",
+				args[0], config.MountNames(), config.BackupNames())
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			logging.Fatal("mount", "error", err)
+		}
+		if err := restic.ResticCheck(config.Binary()); err != nil {
+			fmt.Printf("restic should be installed before running this program: %s not found\n", config.Binary())
+			os.Exit(1)
+		}
+
+		mount, err := config.ReadMount(args[0])
+		if err != nil {
+			logging.Fatal("mount", "error", err)
+		}
+		mountpoint := mount.Mountpoint
+		if len(args) == 2 {
+			mountpoint = args[1]
+		}
+		if mountpoint == "" {
+			logging.Fatal("mount: mountpoint should be given as argument or set in mount config", "mount", mount.Name)
+		}
+
+		backupConf, err := config.ReadBackup(mount.Backup)
+		if err != nil {
+			logging.Fatal("mount", "error", err)
+		}
+		mounter, ok := backupConf.Repository().(restic.Mounter)
+		if !ok {
+			logging.Fatal("mount: backup type does not support mount", "type", backupConf.Type)
+		}
+
+		if err := restic.CheckMountpoint(mountpoint); err != nil {
+			if hint := restic.ErrorHint(err); hint != "" {
+				logging.Fatal("mount", "error", err, "hint", hint)
+			}
+			logging.Fatal("mount", "error", err)
+		}
+
+		slog.Info("mounting repository, press Ctrl-C to unmount", "backup", backupConf.Name, "mountpoint", mountpoint)
+		err = mounter.Mount(cmd.Context(), mountpoint, mount.Options())
+		switch {
+		case errors.Is(err, restic.ErrInterrupted):
+			// Interrupt is the normal way to end mount
+			slog.Info("repository unmounted", "mountpoint", mountpoint)
+		case err != nil:
+			if hint := restic.ErrorHint(err); hint != "" {
+				logging.Fatal("mount", "error", err, "hint", hint)
+			}
+			logging.Fatal("mount", "error", err)
+		}
+	},
+}
+
+func init() {
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// mountCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// mountCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
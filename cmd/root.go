@@ -22,17 +22,29 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/liuminhaw/wrestic-bkp/cmd/config"
+	"github.com/liuminhaw/wrestic-bkp/cmd/doctor"
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
+	"github.com/liuminhaw/wrestic-bkp/cmd/mount"
 	"github.com/liuminhaw/wrestic-bkp/cmd/run"
+	"github.com/liuminhaw/wrestic-bkp/cmd/status"
 	"github.com/liuminhaw/wrestic-bkp/cmd/test"
+	"github.com/liuminhaw/wrestic-bkp/restic"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-var cfgFile string
+var (
+	cfgFile      string
+	resticBinary string
+	logOptions   logging.Options
+)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -48,28 +60,49 @@ var rootCmd = &cobra.Command{
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	rootCmd.AddCommand(config.ConfigCmd)
+	rootCmd.AddCommand(doctor.DoctorCmd)
+	rootCmd.AddCommand(mount.MountCmd)
 	rootCmd.AddCommand(run.RunCmd)
+	rootCmd.AddCommand(status.StatusCmd)
 	rootCmd.AddCommand(test.TestCmd)
-	err := rootCmd.Execute()
+	rootCmd.AddCommand(run.VerifyCmd)
+
+	// Cancel running restic command on interrupt or termination signal
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
+	cobra.OnInitialize(initLogging, initConfig)
 
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is /etc/wrestic-bkp/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&resticBinary, "restic-binary", "", "restic program to run (default is resticBinary in config or restic in PATH)")
+	rootCmd.PersistentFlags().StringVar(&logOptions.Level, "log-level", "info", "log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().StringVar(&logOptions.Format, "log-format", logging.FormatText, "log format: text or json")
+	rootCmd.PersistentFlags().StringVar(&logOptions.File, "log-file", "", "write logs to file instead of stderr")
+	rootCmd.PersistentFlags().IntVar(&logOptions.MaxSizeMB, "log-max-size", 10, "size in MB at which log file is rotated")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
+// initLogging sets up default logger from log flags
+func initLogging() {
+	if err := logging.Setup(logOptions); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
 	if cfgFile != "" {
@@ -88,6 +121,10 @@ func initConfig() {
 		viper.SetConfigName("config.yaml")
 	}
 
+	if resticBinary != "" {
+		restic.SetResticBinary(resticBinary)
+	}
+
 	// viper.AutomaticEnv() // read in environment variables that match
 
 	// If a config file is found, read it in.
@@ -6,10 +6,12 @@ package run
 import (
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"time"
 
 	conf "github.com/liuminhaw/wrestic-bkp/cmd/config"
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
 	"github.com/liuminhaw/wrestic-bkp/restic"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -38,33 +40,83 @@ var backupCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		backupName := args[0]
 
-		requirementsCheck()
-
 		config, err := restic.NewConfig(viper.ConfigFileUsed())
 		if err != nil {
-			log.Fatalf("repository backup: %v\n", err)
+			logging.Fatal("repository backup", "error", err)
 		}
+		requirementsCheck(config)
 		backupConf, err := config.ReadBackup(backupName)
 		if err != nil {
 			if errors.Is(err, restic.ErrConfigBackupNameNotFound) {
 				fmt.Printf("backup %s not found in config file\n", backupName)
 				os.Exit(1)
 			}
-			log.Fatalf("repository backup: %v\n", err)
+			logging.Fatal("repository backup", "error", err)
+		}
+
+		release := acquireRunLock(cmd.Context(), config, backupConf, restic.OperationBackup)
+		defer release()
+
+		backupRepo := backupConf.Repository()
+		summary, err := backupRepo.Backup(cmd.Context())
+		if err != nil {
+			exitOnRunError("repository backup", err)
 		}
+		printBackupSummary(summary)
 
-		backupRepo, err := config.CreateRepositoryStruct(backupConf.Config)
+		// Check repository integrity and consistency after backup
+		now := time.Now()
+		lastCheck, err := restic.LastCheck(config.CheckStateDir(), backupConf.Name)
 		if err != nil {
-			log.Fatalf("repository backup: %v\n", err)
+			slog.Warn("repository backup", "error", err)
+		}
+		if backupConf.Check.Due(lastCheck, now) {
+			checkRepository(cmd.Context(), config, backupConf, backupRepo, backupConf.Check.Options(now))
+		} else {
+			slog.Info("repository check skipped", "afterBackup", backupConf.Check.AfterBackup, "lastCheck", lastCheck)
 		}
-		if err := backupRepo.Backup(); err != nil {
-			log.Fatalf("repository backup: %v\n", err)
+
+		// Restore sample of files from new snapshot when restore drill is due
+		lastVerify, err := restic.LastVerify(config.CheckStateDir(), backupConf.Name)
+		if err != nil {
+			slog.Warn("repository backup", "error", err)
+		}
+		if backupConf.Verify.Due(lastVerify, now) {
+			if err := verifyBackup(cmd.Context(), config, backupConf, backupConf.Verify.Options()); err != nil {
+				exitOnRunError("repository verify", err)
+			}
+		}
+
+		// Copy snapshots to repositories of copyTo backups
+		if len(backupConf.CopyTo) > 0 {
+			copyToTargets(cmd.Context(), config, backupConf, backupRepo, backupConf.CopyTo, restic.CopyOptions{})
 		}
 	},
 }
 
+// printBackupSummary prints result of backup and logs it
+func printBackupSummary(summary restic.BackupSummary) {
+	fmt.Printf("snapshot %s saved\n", summary.SnapshotID)
+	fmt.Printf("Files:  %d new, %d changed, %d unmodified\n",
+		summary.FilesNew, summary.FilesChanged, summary.FilesUnmodified)
+	fmt.Printf("Dirs:   %d new, %d changed, %d unmodified\n",
+		summary.DirsNew, summary.DirsChanged, summary.DirsUnmodified)
+	fmt.Printf("Added:  %s (processed %d files, %s in %s)\n",
+		restic.FormatBytes(summary.DataAdded), summary.TotalFiles,
+		restic.FormatBytes(summary.TotalBytes), summary.Duration.Round(time.Second))
+
+	slog.Info("backup finished",
+		"snapshot", summary.SnapshotID,
+		"filesNew", summary.FilesNew,
+		"filesChanged", summary.FilesChanged,
+		"filesUnmodified", summary.FilesUnmodified,
+		"dataAdded", summary.DataAdded,
+		"duration", summary.Duration)
+}
+
 func init() {
 	RunCmd.AddCommand(backupCmd)
+	addRunLockFlags(backupCmd)
 
 	// Here you will define your flags and configuration settings.
 
@@ -6,15 +6,21 @@ package run
 import (
 	"errors"
 	"fmt"
-	"log"
 	"os"
+	"time"
 
 	conf "github.com/liuminhaw/wrestic-bkp/cmd/config"
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
 	"github.com/liuminhaw/wrestic-bkp/restic"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+var (
+	checkReadDataSubset string
+	checkRotateSubset   bool
+)
+
 // checkCmd represents the check command
 var checkCmd = &cobra.Command{
 	Use:   "check BackupName",
@@ -38,33 +44,45 @@ var checkCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		backupName := args[0]
 
-		requirementsCheck()
-
 		config, err := restic.NewConfig(viper.ConfigFileUsed())
 		if err != nil {
-			log.Fatalf("repository check: %v\n", err)
+			logging.Fatal("repository check", "error", err)
 		}
+		requirementsCheck(config)
 		checkConf, err := config.ReadBackup(backupName)
 		if err != nil {
 			if errors.Is(err, restic.ErrConfigBackupNameNotFound) {
 				fmt.Printf("backup name %s not found in config file\n", backupName)
 				os.Exit(1)
 			}
-			log.Fatalf("repository check: %v\n", err)
+			logging.Fatal("repository check", "error", err)
 		}
 
-		backupRepo, err := config.CreateRepositoryStruct(checkConf.Config)
-		if err != nil {
-			log.Fatalf("repository check :%v\n", err)
+		release := acquireRunLock(cmd.Context(), config, checkConf, restic.OperationCheck)
+		defer release()
+
+		settings := checkConf.Check
+		if cmd.Flags().Changed("read-data-subset") {
+			settings.ReadDataSubset = checkReadDataSubset
+			settings.RotateSubset = false
+		}
+		if cmd.Flags().Changed("rotate-subset") {
+			settings.RotateSubset = checkRotateSubset
 		}
-		if err := backupRepo.Check(); err != nil {
-			log.Fatalf("repository check: %v\n", err)
+		if err := settings.Validate(); err != nil {
+			logging.Fatal("repository check", "error", err)
 		}
+
+		backupRepo := checkConf.Repository()
+		checkRepository(cmd.Context(), config, checkConf, backupRepo, settings.Options(time.Now()))
 	},
 }
 
 func init() {
 	RunCmd.AddCommand(checkCmd)
+	addRunLockFlags(checkCmd)
+	checkCmd.Flags().StringVar(&checkReadDataSubset, "read-data-subset", "", "read subset of pack data, as percentage \"p%\" or group \"n/t\" (default is check readDataSubset config)")
+	checkCmd.Flags().BoolVar(&checkRotateSubset, "rotate-subset", false, "read a different group of data subset each day (default is check rotateSubset config)")
 
 	// Here you will define your flags and configuration settings.
 
@@ -0,0 +1,116 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	conf "github.com/liuminhaw/wrestic-bkp/cmd/config"
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	copyTargets   []string
+	copySnapshots []string
+)
+
+// copyCmd represents the copy command
+var copyCmd = &cobra.Command{
+	Use:   "copy BackupName",
+	Short: "Copy snapshots of BackupName to repositories of other backups",
+	Long: `Copy snapshots of BackupName to repository of each --to backup, default to
+copyTo config of BackupName, without reading backup sources again.
+Target repository is created with same chunker parameters if it does not exist.
+All snapshots missing in target are copied unless --snapshot is given`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		for _, name := range append([]string{args[0]}, copyTargets...) {
+			if !config.IsValidName(name) {
+				return fmt.Errorf("given name '%s' not found in config names: %v", name, conf.ValidConfigNames(config))
+			}
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		backupName := args[0]
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			logging.Fatal("repository copy", "error", err)
+		}
+		requirementsCheck(config)
+		backupConf, err := config.ReadBackup(backupName)
+		if err != nil {
+			if errors.Is(err, restic.ErrConfigBackupNameNotFound) {
+				fmt.Printf("backup %s not found in config file\n", backupName)
+				os.Exit(1)
+			}
+			logging.Fatal("repository copy", "error", err)
+		}
+
+		targets := copyTargets
+		if len(targets) == 0 {
+			targets = backupConf.CopyTo
+		}
+		if len(targets) == 0 {
+			logging.Fatal("repository copy: no target given with --to or set in copyTo config", "backup", backupName)
+		}
+
+		release := acquireRunLock(cmd.Context(), config, backupConf, restic.OperationCopy)
+		defer release()
+
+		repo := backupConf.Repository()
+		snapshots := copySnapshots
+		if browser, ok := repo.(restic.Browser); ok && len(snapshots) > 0 {
+			snapshots = resolveSnapshots(cmd.Context(), "repository copy", browser, snapshots...)
+		}
+		copyToTargets(cmd.Context(), config, backupConf, repo, targets, restic.CopyOptions{Snapshots: snapshots})
+	},
+}
+
+// copyToTargets copies snapshots of backup repository repo to repositories
+// of targets backups, locking each target while copying. Exit if copy fails
+func copyToTargets(ctx context.Context, config *restic.Config, backup restic.Backup,
+	repo restic.ResticRepository, targets []string, opts restic.CopyOptions,
+) {
+	copier, ok := repo.(restic.Copier)
+	if !ok {
+		logging.Fatal("repository copy: backup type does not support copy", "type", backup.Type)
+	}
+
+	for _, target := range targets {
+		targetConf, err := config.ReadBackup(target)
+		if err != nil {
+			logging.Fatal("repository copy", "target", target, "error", err)
+		}
+
+		release := acquireRunLock(ctx, config, targetConf, restic.OperationCopy)
+		err = copier.CopyTo(ctx, targetConf.Repository(), opts)
+		release()
+		if err != nil {
+			exitOnRunError("repository copy to "+target, err)
+		}
+	}
+}
+
+func init() {
+	RunCmd.AddCommand(copyCmd)
+	addRunLockFlags(copyCmd)
+	copyCmd.Flags().StringSliceVar(&copyTargets, "to", nil, "backup to copy snapshots to, can be repeated (default is copyTo config)")
+	copyCmd.Flags().StringSliceVar(&copySnapshots, "snapshot", nil, "snapshot to copy, can be repeated (default all missing in target)")
+}
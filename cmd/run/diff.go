@@ -0,0 +1,154 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package run
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	conf "github.com/liuminhaw/wrestic-bkp/cmd/config"
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	diffLive bool
+	diffPath string
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff BackupName [snapshotA] [snapshotB]",
+	Short: "Show changes between two snapshots or a snapshot and live sources",
+	Long: `Show paths added (+), removed (-) and modified (M, T for type, U for metadata)
+between snapshotA and snapshotB, default to the last two snapshots.
+Snapshots are referenced by ID, "latest" or "latest~N" for the Nth snapshot before latest.
+With --live, snapshotA (default latest) is compared with the live backup sources`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.RangeArgs(1, 3)(cmd, args); err != nil {
+			return err
+		}
+		if diffLive && len(args) > 2 {
+			return errors.New("only one snapshot is compared with --live")
+		}
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		if !config.IsValidName(args[0]) {
+			return fmt.Errorf("given name '%s' not found in config names: %v", args[0], conf.ValidConfigNames(config))
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		backupName := args[0]
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			logging.Fatal("repository diff", "error", err)
+		}
+		requirementsCheck(config)
+		backupConf, err := config.ReadBackup(backupName)
+		if err != nil {
+			if errors.Is(err, restic.ErrConfigBackupNameNotFound) {
+				fmt.Printf("backup %s not found in config file\n", backupName)
+				os.Exit(1)
+			}
+			logging.Fatal("repository diff", "error", err)
+		}
+
+		repo := backupConf.Repository()
+		browser, ok := repo.(restic.Browser)
+		differ, diffOk := repo.(restic.Differ)
+		if !ok || !diffOk {
+			logging.Fatal("repository diff: backup type does not support diff", "type", backupConf.Type)
+		}
+
+		refs := []string{"latest~1", "latest"}
+		if diffLive {
+			refs = []string{"latest"}
+		}
+		copy(refs, args[1:])
+
+		ids := resolveSnapshots(cmd.Context(), "repository diff", browser, refs...)
+
+		var result restic.DiffResult
+		if diffLive {
+			result, err = differ.DiffLive(cmd.Context(), ids[0])
+		} else {
+			result, err = differ.Diff(cmd.Context(), ids[0], ids[1])
+		}
+		if err != nil {
+			exitOnRunError("repository diff", err)
+		}
+		printDiff(result, diffPath)
+	},
+}
+
+// printDiff prints changes of result under path prefix with size deltas,
+// followed by added and removed totals
+func printDiff(result restic.DiffResult, prefix string) {
+	to := "live sources"
+	if result.To != "" {
+		to = "snapshot " + shortSnapshotID(result.To)
+	}
+	fmt.Printf("comparing snapshot %s to %s\n\n", shortSnapshotID(result.From), to)
+
+	changes := result.Under(prefix)
+	if len(changes) == 0 {
+		fmt.Println("no changes")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, change := range changes {
+			delta := ""
+			if !strings.HasSuffix(change.Path, "/") && change.SizeDelta != 0 {
+				delta = formatSizeDelta(change.SizeDelta)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", change.Modifier, delta, change.Path)
+		}
+		w.Flush()
+	}
+
+	if prefix != "" {
+		return
+	}
+	fmt.Printf("\nchanged files: %d\n", result.ChangedFiles)
+	fmt.Printf("added:   %d files, %d dirs, %s\n",
+		result.Added.Files, result.Added.Dirs, restic.FormatBytes(result.Added.Bytes))
+	if result.To != "" {
+		fmt.Printf("removed: %d files, %d dirs, %s\n",
+			result.Removed.Files, result.Removed.Dirs, restic.FormatBytes(result.Removed.Bytes))
+	}
+}
+
+// formatSizeDelta formats size change in bytes with sign, e.g. "+1.5 MiB"
+func formatSizeDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + restic.FormatBytes(uint64(-delta))
+	}
+
+	return "+" + restic.FormatBytes(uint64(delta))
+}
+
+// shortSnapshotID returns first 8 characters of snapshot id
+func shortSnapshotID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+
+	return id
+}
+
+func init() {
+	RunCmd.AddCommand(diffCmd)
+	diffCmd.Flags().BoolVar(&diffLive, "live", false, "compare snapshot with live backup sources")
+	diffCmd.Flags().StringVar(&diffPath, "path", "", "show only changes under path")
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package run
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	conf "github.com/liuminhaw/wrestic-bkp/cmd/config"
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	dumpOptions restic.DumpOptions
+	dumpOutput  string
+)
+
+// dumpCmd represents the dump command
+var dumpCmd = &cobra.Command{
+	Use:   "dump BackupName snapshot path",
+	Short: "Write file or directory archive from snapshot to stdout or file",
+	Long: `Write file at path in snapshot to stdout, or to file given by --output.
+Directory is written as tar archive, or zip archive with --archive zip.
+Snapshot is referenced by ID, "latest" or "latest~N" for the Nth snapshot before latest`,
+	Example: `  wrestic-bkp run dump db latest /backup/db.sql | psql
+  wrestic-bkp run dump web latest~1 /etc/nginx --archive zip -o nginx.zip`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(3)(cmd, args); err != nil {
+			return err
+		}
+		if err := dumpOptions.Validate(); err != nil {
+			return err
+		}
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		if !config.IsValidName(args[0]) {
+			return fmt.Errorf("given name '%s' not found in config names: %v", args[0], conf.ValidConfigNames(config))
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		backupName, ref, path := args[0], args[1], args[2]
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			logging.Fatal("repository dump", "error", err)
+		}
+		requirementsCheck(config)
+		backupConf, err := config.ReadBackup(backupName)
+		if err != nil {
+			if errors.Is(err, restic.ErrConfigBackupNameNotFound) {
+				fmt.Fprintf(os.Stderr, "backup %s not found in config file\n", backupName)
+				os.Exit(1)
+			}
+			logging.Fatal("repository dump", "error", err)
+		}
+
+		repo := backupConf.Repository()
+		dumper, ok := repo.(restic.Dumper)
+		if !ok {
+			logging.Fatal("repository dump: backup type does not support dump", "type", backupConf.Type)
+		}
+		snapshot := ref
+		if browser, ok := repo.(restic.Browser); ok {
+			snapshot = resolveSnapshots(cmd.Context(), "repository dump", browser, ref)[0]
+		}
+
+		var out io.Writer = os.Stdout
+		if dumpOutput != "" {
+			file, err := os.Create(dumpOutput)
+			if err != nil {
+				logging.Fatal("repository dump", "error", err)
+			}
+			defer file.Close()
+			out = file
+		}
+
+		if err := dumper.Dump(cmd.Context(), snapshot, path, dumpOptions, out); err != nil {
+			if dumpOutput != "" {
+				// Do not leave partial file behind
+				os.Remove(dumpOutput)
+			}
+			exitOnRunError("repository dump", err)
+		}
+	},
+}
+
+func init() {
+	RunCmd.AddCommand(dumpCmd)
+	dumpCmd.Flags().StringVar(&dumpOptions.Archive, "archive", "", "archive format of directory: tar or zip (default tar)")
+	dumpCmd.Flags().StringVarP(&dumpOutput, "output", "o", "", "write to file instead of stdout")
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package run
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	conf "github.com/liuminhaw/wrestic-bkp/cmd/config"
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var findOptions restic.FindOptions
+
+// findCmd represents the find command
+var findCmd = &cobra.Command{
+	Use:   "find BackupName PATTERN",
+	Short: "Find files matching pattern in all snapshots",
+	Long: `Find files and directories matching PATTERN, e.g. "nginx.conf" or "*.conf",
+in all snapshots of backup, listed with snapshot, size and modification time`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(2)(cmd, args); err != nil {
+			return err
+		}
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		if !config.IsValidName(args[0]) {
+			return fmt.Errorf("given name '%s' not found in config names: %v", args[0], conf.ValidConfigNames(config))
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		backupName, pattern := args[0], args[1]
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			logging.Fatal("repository find", "error", err)
+		}
+		requirementsCheck(config)
+		backupConf, err := config.ReadBackup(backupName)
+		if err != nil {
+			if errors.Is(err, restic.ErrConfigBackupNameNotFound) {
+				fmt.Printf("backup %s not found in config file\n", backupName)
+				os.Exit(1)
+			}
+			logging.Fatal("repository find", "error", err)
+		}
+
+		browser, ok := backupConf.Repository().(restic.Browser)
+		if !ok {
+			logging.Fatal("repository find: backup type does not support find", "type", backupConf.Type)
+		}
+
+		matches, err := browser.Find(cmd.Context(), pattern, findOptions)
+		if err != nil {
+			exitOnRunError("repository find", err)
+		}
+		if len(matches) == 0 {
+			fmt.Printf("no files matching %s found\n", pattern)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SNAPSHOT\tSIZE\tMODIFIED\tPATH")
+		for _, match := range matches {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				shortSnapshotID(match.Snapshot), nodeSize(match.Node),
+				match.ModTime.Local().Format(time.DateTime), match.Path)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	RunCmd.AddCommand(findCmd)
+	findCmd.Flags().StringVar(&findOptions.Oldest, "oldest", "", "oldest modification time of files, e.g. 2024-05-01")
+	findCmd.Flags().StringVar(&findOptions.Newest, "newest", "", "newest modification time of files, e.g. 2024-05-31")
+	findCmd.Flags().StringSliceVar(&findOptions.Paths, "path", nil, "search only snapshots including path, can be repeated")
+}
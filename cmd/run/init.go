@@ -6,10 +6,10 @@ package run
 import (
 	"errors"
 	"fmt"
-	"log"
 	"os"
 
 	conf "github.com/liuminhaw/wrestic-bkp/cmd/config"
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
 	"github.com/liuminhaw/wrestic-bkp/restic"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -38,30 +38,28 @@ var initCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		backupName := args[0]
 
-		requirementsCheck()
-
 		config, err := restic.NewConfig(viper.ConfigFileUsed())
 		if err != nil {
-			log.Fatalf("repository init: %v\n", err)
+			logging.Fatal("repository init", "error", err)
 		}
+		requirementsCheck(config)
 		backupConf, err := config.ReadBackup(backupName)
 		if err != nil {
 			if errors.Is(err, restic.ErrConfigBackupNameNotFound) {
 				fmt.Printf("backup %s not found in config file\n", backupName)
 				os.Exit(1)
 			}
-			log.Fatalf("repository init: %v\n", err)
+			logging.Fatal("repository init", "error", err)
 		}
 
-		backupRepo, err := config.CreateRepositoryStruct(backupConf.Config)
-		if err != nil {
-			log.Fatalf("repository init: %v\n", err)
-		}
-		output, err := backupRepo.Init()
+		release := acquireRunLock(cmd.Context(), config, backupConf, restic.OperationInit)
+		defer release()
+
+		backupRepo := backupConf.Repository()
+		output, err := backupRepo.Init(cmd.Context())
 		if err != nil {
 			fmt.Print(string(output))
-			fmt.Printf("wrestic init: %v\n", err)
-			os.Exit(1)
+			exitOnRunError("wrestic init", err)
 		}
 		fmt.Println(string(output))
 	},
@@ -69,6 +67,7 @@ var initCmd = &cobra.Command{
 
 func init() {
 	RunCmd.AddCommand(initCmd)
+	addRunLockFlags(initCmd)
 
 	// Here you will define your flags and configuration settings.
 
@@ -0,0 +1,274 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+var (
+	keyOptions            restic.KeyOptions
+	keyNewPasswordFile    string
+	keyNewPasswordCommand string
+	keyUpdateConfig       bool
+)
+
+// keyCmd represents the key command
+var keyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Manage repository keys",
+	Long: `List, add and remove keys of backup repository, or change its password.
+New password is read from --new-password-file, output of --new-password-command
+or typed at prompt`,
+}
+
+var keyListCmd = &cobra.Command{
+	Use:   "list BackupName",
+	Short: "List keys of repository",
+	Args:  validBackupArgs(1, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, backupConf, manager := readKeyManager(args[0])
+
+		keys, err := manager.Keys(cmd.Context())
+		if err != nil {
+			exitOnRunError("repository key list", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, " \tID\tUSER\tHOST\tCREATED")
+		for _, key := range keys {
+			current := " "
+			if key.Current {
+				current = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", current, shortSnapshotID(key.ID), key.UserName, key.HostName, key.Created)
+		}
+		w.Flush()
+		slog.Debug("repository key list", "backup", backupConf.Name, "keys", len(keys))
+	},
+}
+
+var keyAddCmd = &cobra.Command{
+	Use:   "add BackupName",
+	Short: "Add key with new password to repository",
+	Args:  validBackupArgs(1, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, _, manager := readKeyManager(args[0])
+
+		password, _ := readNewPassword()
+		if err := manager.AddKey(cmd.Context(), password, keyOptions); err != nil {
+			exitOnRunError("repository key add", err)
+		}
+		fmt.Println("key added")
+	},
+}
+
+var keyRemoveCmd = &cobra.Command{
+	Use:   "remove BackupName KeyID",
+	Short: "Remove key from repository",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(2)(cmd, args); err != nil {
+			return err
+		}
+		return validBackupArgs(1, 2)(cmd, args[:1])
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		_, _, manager := readKeyManager(args[0])
+
+		if err := manager.RemoveKey(cmd.Context(), args[1]); err != nil {
+			exitOnRunError("repository key remove", err)
+		}
+		fmt.Printf("key %s removed\n", args[1])
+	},
+}
+
+var keyPasswdCmd = &cobra.Command{
+	Use:   "passwd BackupName [BackupName...]",
+	Short: "Change password of repositories",
+	Long: `Change password of repository of each BackupName to new password.
+Repository password in config is shared by all backups, so --update-config,
+which rewrites repository password in config file after success, requires
+every backup in config to be given`,
+	Args: validBackupArgs(1, -1),
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			logging.Fatal("repository key passwd", "error", err)
+		}
+		if keyUpdateConfig {
+			missing := []string{}
+			for _, name := range config.BackupNames() {
+				if !slices.Contains(args, name) {
+					missing = append(missing, name)
+				}
+			}
+			if len(missing) > 0 {
+				logging.Fatal("repository key passwd: --update-config requires password change of all backups", "missing", missing)
+			}
+		}
+
+		managers := []restic.KeyManager{}
+		for _, name := range args {
+			_, _, manager := readKeyManager(name)
+			managers = append(managers, manager)
+		}
+
+		password, source := readNewPassword()
+		for i, manager := range managers {
+			if err := manager.ChangePassword(cmd.Context(), password); err != nil {
+				if i > 0 {
+					slog.Error("repository key passwd: password already changed", "backups", args[:i])
+				}
+				exitOnRunError("repository key passwd "+args[i], err)
+			}
+			fmt.Printf("password of backup %s changed\n", args[i])
+		}
+
+		if keyUpdateConfig {
+			if err := restic.UpdateConfigRepository(viper.ConfigFileUsed(), source); err != nil {
+				logging.Fatal("repository key passwd: password changed but config not updated", "error", err)
+			}
+			fmt.Printf("repository password updated in %s\n", viper.ConfigFileUsed())
+		}
+	},
+}
+
+// readKeyManager reads backup of backupName from config and returns its
+// repository as KeyManager. Exit if backup type does not support keys
+func readKeyManager(backupName string) (*restic.Config, restic.Backup, restic.KeyManager) {
+	config, err := restic.NewConfig(viper.ConfigFileUsed())
+	if err != nil {
+		logging.Fatal("repository key", "error", err)
+	}
+	requirementsCheck(config)
+	backupConf, err := config.ReadBackup(backupName)
+	if err != nil {
+		if errors.Is(err, restic.ErrConfigBackupNameNotFound) {
+			fmt.Printf("backup %s not found in config file\n", backupName)
+			os.Exit(1)
+		}
+		logging.Fatal("repository key", "error", err)
+	}
+
+	manager, ok := backupConf.Repository().(restic.KeyManager)
+	if !ok {
+		logging.Fatal("repository key: backup type does not support key management", "type", backupConf.Type)
+	}
+
+	return config, backupConf, manager
+}
+
+// readNewPassword reads new password from file, command or prompt, returning
+// it with repository config of its source. Exit if password cannot be read
+func readNewPassword() (string, restic.ConfigRepository) {
+	var password string
+	var source restic.ConfigRepository
+	var err error
+
+	switch {
+	case keyNewPasswordFile != "" && keyNewPasswordCommand != "":
+		err = errors.New("only one of --new-password-file and --new-password-command should be given")
+	case keyNewPasswordFile != "":
+		var path string
+		if path, err = filepath.Abs(keyNewPasswordFile); err == nil {
+			password, err = readPasswordFile(path)
+			source.PasswordFile = path
+		}
+	case keyNewPasswordCommand != "":
+		password, err = runPasswordCommand(context.Background(), keyNewPasswordCommand)
+		source.PasswordCommand = keyNewPasswordCommand
+	default:
+		password, err = promptNewPassword()
+		source.Password = password
+	}
+	if err == nil && password == "" {
+		err = errors.New("new password is empty")
+	}
+	if err != nil {
+		logging.Fatal("read new password", "error", err)
+	}
+
+	return password, source
+}
+
+// readPasswordFile returns first line of password file, as read by restic
+func readPasswordFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	password, _, _ := strings.Cut(string(data), "\n")
+
+	return strings.TrimSuffix(password, "\r"), nil
+}
+
+// runPasswordCommand returns first line of command output, as read by restic
+func runPasswordCommand(ctx context.Context, command string) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("password command: %w", err)
+	}
+	password, _, _ := strings.Cut(string(output), "\n")
+
+	return strings.TrimSuffix(password, "\r"), nil
+}
+
+// promptNewPassword reads new password twice from terminal on stdin without echo
+func promptNewPassword() (string, error) {
+	stdin := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdin) {
+		return "", errors.New("password prompt needs terminal, use --new-password-file or --new-password-command")
+	}
+
+	read := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		password, err := term.ReadPassword(stdin)
+		fmt.Fprintln(os.Stderr)
+		return string(password), err
+	}
+
+	password, err := read("enter new password: ")
+	if err != nil {
+		return "", fmt.Errorf("password prompt: %w", err)
+	}
+	again, err := read("enter password again: ")
+	if err != nil {
+		return "", fmt.Errorf("password prompt: %w", err)
+	}
+	if password != again {
+		return "", errors.New("passwords do not match")
+	}
+
+	return password, nil
+}
+
+func init() {
+	RunCmd.AddCommand(keyCmd)
+	keyCmd.AddCommand(keyListCmd, keyAddCmd, keyRemoveCmd, keyPasswdCmd)
+
+	for _, cmd := range []*cobra.Command{keyAddCmd, keyPasswdCmd} {
+		cmd.Flags().StringVar(&keyNewPasswordFile, "new-password-file", "", "read new password from first line of file")
+		cmd.Flags().StringVar(&keyNewPasswordCommand, "new-password-command", "", "read new password from first line of command output")
+	}
+	keyAddCmd.Flags().StringVar(&keyOptions.User, "user", "", "user name of new key (default current user)")
+	keyAddCmd.Flags().StringVar(&keyOptions.Host, "host", "", "host name of new key (default current host)")
+	keyPasswdCmd.Flags().BoolVar(&keyUpdateConfig, "update-config", false, "rewrite repository password in config file after password is changed")
+}
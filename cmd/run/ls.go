@@ -0,0 +1,114 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package run
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	conf "github.com/liuminhaw/wrestic-bkp/cmd/config"
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	lsLong      bool
+	lsRecursive bool
+)
+
+// lsCmd represents the ls command
+var lsCmd = &cobra.Command{
+	Use:   "ls BackupName [snapshot] [path]",
+	Short: "List files in snapshot, default to latest snapshot",
+	Long: `List files and directories in snapshot with size and modification time.
+Snapshot is referenced by ID, "latest" or "latest~N" for the Nth snapshot before latest.
+Without path, all files of snapshot are listed. With path, only its entries are listed
+unless --recursive is set`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.RangeArgs(1, 3)(cmd, args); err != nil {
+			return err
+		}
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		if !config.IsValidName(args[0]) {
+			return fmt.Errorf("given name '%s' not found in config names: %v", args[0], conf.ValidConfigNames(config))
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		backupName := args[0]
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			logging.Fatal("repository ls", "error", err)
+		}
+		requirementsCheck(config)
+		backupConf, err := config.ReadBackup(backupName)
+		if err != nil {
+			if errors.Is(err, restic.ErrConfigBackupNameNotFound) {
+				fmt.Printf("backup %s not found in config file\n", backupName)
+				os.Exit(1)
+			}
+			logging.Fatal("repository ls", "error", err)
+		}
+
+		browser, ok := backupConf.Repository().(restic.Browser)
+		if !ok {
+			logging.Fatal("repository ls: backup type does not support ls", "type", backupConf.Type)
+		}
+
+		ref := "latest"
+		if len(args) > 1 {
+			ref = args[1]
+		}
+		opts := restic.LsOptions{Recursive: lsRecursive}
+		if len(args) > 2 {
+			opts.Path = args[2]
+		}
+
+		snapshot := resolveSnapshots(cmd.Context(), "repository ls", browser, ref)[0]
+		nodes, err := browser.Ls(cmd.Context(), snapshot, opts)
+		if err != nil {
+			exitOnRunError("repository ls", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if lsLong {
+			fmt.Fprintln(w, "PERMISSIONS\tSIZE\tMODIFIED\tPATH")
+		} else {
+			fmt.Fprintln(w, "SIZE\tMODIFIED\tPATH")
+		}
+		for _, node := range nodes {
+			if lsLong {
+				fmt.Fprintf(w, "%s\t", node.Permissions)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", nodeSize(node), node.ModTime.Local().Format(time.DateTime), node.Path)
+		}
+		w.Flush()
+	},
+}
+
+// nodeSize returns formatted size of file node, empty for other nodes
+func nodeSize(node restic.Node) string {
+	if node.Type != "file" {
+		return ""
+	}
+
+	return restic.FormatBytes(node.Size)
+}
+
+func init() {
+	RunCmd.AddCommand(lsCmd)
+	lsCmd.Flags().BoolVarP(&lsLong, "long", "l", false, "show permissions of files")
+	lsCmd.Flags().BoolVar(&lsRecursive, "recursive", false, "list entries of path recursively")
+}
@@ -0,0 +1,55 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package run
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate BackupName [migration]",
+	Short: "Apply migration to repository, e.g. upgrade_repo_v2",
+	Long: `Apply migration to repository, or list migrations available to repository if
+migration is not given. Asks for confirmation unless --yes is given, runs are
+recorded in run log of stateDir`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.RangeArgs(1, 2)(cmd, args); err != nil {
+			return err
+		}
+		return validBackupArgs(1, 1)(cmd, args[:1])
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		config, backupConf, maintainer := readMaintainer("repository migrate", args[0])
+
+		if len(args) == 1 {
+			if err := maintainer.Migrate(cmd.Context(), ""); err != nil {
+				exitOnRunError("repository migrate", err)
+			}
+			return
+		}
+
+		migration := args[1]
+		confirmRun(fmt.Sprintf("apply migration %s to repository %s of backup %s", migration, backupConf.Location(), backupConf.Name))
+		release := acquireRunLock(cmd.Context(), config, backupConf, restic.OperationMigrate)
+		defer release()
+
+		started := time.Now()
+		err := maintainer.Migrate(cmd.Context(), migration)
+		recordRun(config, backupConf, restic.OperationMigrate, started, err)
+		if err != nil {
+			exitOnRunError("repository migrate", err)
+		}
+	},
+}
+
+func init() {
+	RunCmd.AddCommand(migrateCmd)
+	addRunLockFlags(migrateCmd)
+	addConfirmFlags(migrateCmd)
+}
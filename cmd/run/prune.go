@@ -0,0 +1,60 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package run
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+)
+
+var pruneOptions restic.PruneOptions
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune BackupName",
+	Short: "Remove data not referenced by snapshots from repository",
+	Long: `Remove data of forgotten snapshots from repository, repacking partly used packs
+until at most --max-unused data is left unused and --max-repack-size data is repacked.
+Asks for confirmation unless --yes is given, runs are recorded in run log of stateDir`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := pruneOptions.Validate(); err != nil {
+			return err
+		}
+
+		return validBackupArgs(1, 1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		config, backupConf, maintainer := readMaintainer("repository prune", args[0])
+
+		if pruneOptions.DryRun {
+			if err := maintainer.Prune(cmd.Context(), pruneOptions); err != nil {
+				exitOnRunError("repository prune", err)
+			}
+			return
+		}
+
+		confirmRun(fmt.Sprintf("prune repository %s of backup %s", backupConf.Location(), backupConf.Name))
+		release := acquireRunLock(cmd.Context(), config, backupConf, restic.OperationPrune)
+		defer release()
+
+		started := time.Now()
+		err := maintainer.Prune(cmd.Context(), pruneOptions)
+		recordRun(config, backupConf, restic.OperationPrune, started, err)
+		if err != nil {
+			exitOnRunError("repository prune", err)
+		}
+	},
+}
+
+func init() {
+	RunCmd.AddCommand(pruneCmd)
+	addRunLockFlags(pruneCmd)
+	addConfirmFlags(pruneCmd)
+	pruneCmd.Flags().StringVar(&pruneOptions.MaxUnused, "max-unused", "", "unused data tolerated in repository, as percentage, size or unlimited (default 5%)")
+	pruneCmd.Flags().StringVar(&pruneOptions.MaxRepackSize, "max-repack-size", "", "limit data repacked by prune, e.g. 10G")
+	pruneCmd.Flags().BoolVar(&pruneOptions.DryRun, "dry-run", false, "only show what would be removed")
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package run
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+)
+
+var repairOptions restic.RepairOptions
+
+// repairCmd represents the repair command
+var repairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Repair damaged repository",
+	Long: `Rebuild repository index, remove damaged data from snapshots or salvage damaged packs.
+Asks for confirmation unless --yes is given, runs are recorded in run log of stateDir.
+Requires restic 0.16`,
+}
+
+var repairIndexCmd = &cobra.Command{
+	Use:   "index BackupName",
+	Short: "Rebuild repository index from packs",
+	Args:  validBackupArgs(1, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runRepair(cmd, args[0], restic.RepairIndex)
+	},
+}
+
+var repairSnapshotsCmd = &cobra.Command{
+	Use:   "snapshots BackupName",
+	Short: "Save snapshots again without damaged or missing data",
+	Args:  validBackupArgs(1, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runRepair(cmd, args[0], restic.RepairSnapshots)
+	},
+}
+
+var repairPacksCmd = &cobra.Command{
+	Use:   "packs BackupName PackID [PackID...]",
+	Short: "Salvage readable blobs of damaged packs reported by check",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.MinimumNArgs(2)(cmd, args); err != nil {
+			return err
+		}
+		return validBackupArgs(1, 1)(cmd, args[:1])
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		repairOptions.Packs = args[1:]
+		runRepair(cmd, args[0], restic.RepairPacks)
+	},
+}
+
+// runRepair repairs target of repository of backupName after confirmation
+// and records the run. Exit if repair fails
+func runRepair(cmd *cobra.Command, backupName, target string) {
+	action := "repository repair " + target
+	config, backupConf, maintainer := readMaintainer(action, backupName)
+	if err := repairOptions.Validate(target); err != nil {
+		logging.Fatal(action, "error", err)
+	}
+
+	confirmRun(fmt.Sprintf("repair %s of repository %s of backup %s", target, backupConf.Location(), backupConf.Name))
+	release := acquireRunLock(cmd.Context(), config, backupConf, restic.OperationRepair)
+	defer release()
+
+	started := time.Now()
+	err := maintainer.Repair(cmd.Context(), target, repairOptions)
+	recordRun(config, backupConf, restic.OperationRepair, started, err)
+	if err != nil {
+		exitOnRunError(action, err)
+	}
+}
+
+func init() {
+	RunCmd.AddCommand(repairCmd)
+	repairCmd.AddCommand(repairIndexCmd, repairSnapshotsCmd, repairPacksCmd)
+	for _, cmd := range repairCmd.Commands() {
+		addRunLockFlags(cmd)
+		addConfirmFlags(cmd)
+	}
+	repairSnapshotsCmd.Flags().BoolVar(&repairOptions.Forget, "forget", false, "remove snapshots whose data is lost instead of saving what is left")
+}
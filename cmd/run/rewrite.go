@@ -0,0 +1,85 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package run
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	rewriteOptions restic.RewriteOptions
+	rewriteApply   bool
+)
+
+// rewriteCmd represents the rewrite command
+var rewriteCmd = &cobra.Command{
+	Use:   "rewrite BackupName",
+	Short: "Remove paths matching --exclude from snapshots",
+	Long: `Save snapshots again without paths matching --exclude patterns, e.g. files
+included by mistake. All snapshots are rewritten unless --snapshot is given.
+Rewrite is a dry run until --apply is given. With --forget original snapshots
+are removed, their data stays in repository until it is pruned. Requires restic 0.15`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := rewriteOptions.Validate(); err != nil {
+			return err
+		}
+
+		return validBackupArgs(1, 1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		backupName := args[0]
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			logging.Fatal("repository rewrite", "error", err)
+		}
+		requirementsCheck(config)
+		backupConf, err := config.ReadBackup(backupName)
+		if err != nil {
+			if errors.Is(err, restic.ErrConfigBackupNameNotFound) {
+				fmt.Printf("backup %s not found in config file\n", backupName)
+				os.Exit(1)
+			}
+			logging.Fatal("repository rewrite", "error", err)
+		}
+
+		repo := backupConf.Repository()
+		rewriter, ok := repo.(restic.Rewriter)
+		if !ok {
+			logging.Fatal("repository rewrite: backup type does not support rewrite", "type", backupConf.Type)
+		}
+
+		if rewriteApply {
+			release := acquireRunLock(cmd.Context(), config, backupConf, restic.OperationRewrite)
+			defer release()
+		}
+
+		opts := rewriteOptions
+		if browser, ok := repo.(restic.Browser); ok && len(opts.Snapshots) > 0 {
+			opts.Snapshots = resolveSnapshots(cmd.Context(), "repository rewrite", browser, opts.Snapshots...)
+		}
+		if err := rewriter.Rewrite(cmd.Context(), opts, rewriteApply); err != nil {
+			exitOnRunError("repository rewrite", err)
+		}
+		if !rewriteApply {
+			fmt.Println("\ndry run: no snapshots rewritten, run with --apply to rewrite them")
+		}
+	},
+}
+
+func init() {
+	RunCmd.AddCommand(rewriteCmd)
+	addRunLockFlags(rewriteCmd)
+	rewriteCmd.Flags().StringSliceVar(&rewriteOptions.Excludes, "exclude", nil, "exclude paths matching pattern, can be repeated")
+	rewriteCmd.Flags().StringSliceVar(&rewriteOptions.Snapshots, "snapshot", nil, "rewrite only snapshot ID or \"latest\", can be repeated")
+	rewriteCmd.Flags().BoolVar(&rewriteOptions.Forget, "forget", false, "remove original snapshots after rewrite")
+	rewriteCmd.Flags().BoolVar(&rewriteApply, "apply", false, "rewrite snapshots instead of dry run")
+}
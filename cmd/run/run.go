@@ -4,11 +4,32 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package run
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"strings"
+	"time"
 
+	conf "github.com/liuminhaw/wrestic-bkp/cmd/config"
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
 	"github.com/liuminhaw/wrestic-bkp/restic"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// interruptedExitCode is the exit status when restic run is interrupted by signal
+	interruptedExitCode int = 130
+	// alreadyRunningExitCode is the exit status when another run of backup is in progress
+	alreadyRunningExitCode int = 75
+)
+
+var (
+	runLockWait time.Duration
+	confirmYes  bool
 )
 
 // repositoryCmd represents the repository command
@@ -35,9 +56,201 @@ func init() {
 
 // requirementsCheck check needed requirements for program execution.
 // Exit if any requirment is not met
-func requirementsCheck() {
-	if err := restic.ResticCheck(); err != nil {
-		fmt.Println("restic should be installed before running this program")
+func requirementsCheck(config *restic.Config) {
+	if err := restic.ResticCheck(config.Binary()); err != nil {
+		fmt.Printf("restic should be installed before running this program: %s not found\n", config.Binary())
 		os.Exit(1)
 	}
 }
+
+// exitOnRunError reports err from restic run of action with its hint if any, and exits.
+// Runs stopped by signal are reported as interrupted instead of failed
+func exitOnRunError(action string, err error) {
+	if errors.Is(err, restic.ErrInterrupted) {
+		slog.Warn(action + ": interrupted")
+		os.Exit(interruptedExitCode)
+	}
+	if hint := restic.ErrorHint(err); hint != "" {
+		logging.Fatal(action, "error", err, "hint", hint)
+	}
+	logging.Fatal(action, "error", err)
+}
+
+// checkRepository checks repository of backup with opts and records time of
+// successful check for weekly check after backup. Exit if check fails
+func checkRepository(ctx context.Context, config *restic.Config, backup restic.Backup,
+	repo restic.ResticRepository, opts restic.CheckOptions,
+) {
+	if err := repo.Check(ctx, opts); err != nil {
+		exitOnRunError("repository check", err)
+	}
+	if err := restic.RecordCheck(config.CheckStateDir(), backup.Name, time.Now()); err != nil {
+		slog.Warn("repository check", "error", err)
+	}
+}
+
+// resolveSnapshots returns snapshot IDs of refs for action, listing snapshots
+// only if a ref is relative to latest snapshot. Exit if a ref is not resolved
+func resolveSnapshots(ctx context.Context, action string, browser restic.Browser, refs ...string) []string {
+	ids := make([]string, len(refs))
+	copy(ids, refs)
+
+	var snapshots []restic.Snapshot
+	for i, ref := range refs {
+		if !restic.NeedsResolve(ref) {
+			continue
+		}
+		if snapshots == nil {
+			var err error
+			if snapshots, err = browser.SnapshotList(ctx); err != nil {
+				exitOnRunError(action, err)
+			}
+		}
+		id, err := restic.ResolveSnapshot(snapshots, ref)
+		if err != nil {
+			logging.Fatal(action, "error", err)
+		}
+		ids[i] = id
+	}
+
+	return ids
+}
+
+// validBackupArgs returns cobra args validator accepting min to max (unlimited
+// if negative) backup names in config
+func validBackupArgs(min, max int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := cobra.MinimumNArgs(min)(cmd, args); err != nil {
+			return err
+		}
+		if max >= 0 {
+			if err := cobra.MaximumNArgs(max)(cmd, args); err != nil {
+				return err
+			}
+		}
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		for _, name := range args {
+			if !config.IsValidName(name) {
+				return fmt.Errorf("given name '%s' not found in config names: %v", name, conf.ValidConfigNames(config))
+			}
+		}
+
+		return nil
+	}
+}
+
+// addConfirmFlags adds flags of commands asking confirmation with confirmRun
+func addConfirmFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&confirmYes, "yes", "y", false, "run without asking for confirmation")
+}
+
+// confirmRun asks on stdin for confirmation of action unless --yes is given.
+// Exit if action is not confirmed
+func confirmRun(action string) {
+	if confirmYes {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "%s, continue? [y/N] ", action)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		fmt.Fprintln(os.Stderr)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return
+	}
+	fmt.Println("aborted, use --yes to run without confirmation")
+	os.Exit(1)
+}
+
+// recordRun appends run of operation on backup started at started to run log,
+// as failed if err is not nil
+func recordRun(config *restic.Config, backup restic.Backup, operation string, started time.Time, err error) {
+	record := restic.RunRecord{
+		Backup:     backup.Name,
+		Repository: backup.Location(),
+		Operation:  operation,
+		Args:       os.Args[1:],
+		Started:    started,
+		Finished:   time.Now(),
+		Status:     restic.RunSucceeded,
+	}
+	switch {
+	case errors.Is(err, restic.ErrInterrupted):
+		record.Status = restic.RunInterrupted
+	case err != nil:
+		record.Status = restic.RunFailed
+		record.Error = err.Error()
+	}
+
+	slog.Info("run recorded", "backup", backup.Name, "operation", operation, "status", record.Status)
+	if err := restic.AppendRunRecord(config.RunLogDir(), record); err != nil {
+		slog.Warn(operation, "error", err)
+	}
+}
+
+// readMaintainer reads backup of backupName from config and returns its
+// repository as Maintainer. Exit if backup type does not support maintenance
+func readMaintainer(action, backupName string) (*restic.Config, restic.Backup, restic.Maintainer) {
+	config, err := restic.NewConfig(viper.ConfigFileUsed())
+	if err != nil {
+		logging.Fatal(action, "error", err)
+	}
+	requirementsCheck(config)
+	backupConf, err := config.ReadBackup(backupName)
+	if err != nil {
+		if errors.Is(err, restic.ErrConfigBackupNameNotFound) {
+			fmt.Printf("backup %s not found in config file\n", backupName)
+			os.Exit(1)
+		}
+		logging.Fatal(action, "error", err)
+	}
+
+	maintainer, ok := backupConf.Repository().(restic.Maintainer)
+	if !ok {
+		logging.Fatal(action+": backup type does not support maintenance", "type", backupConf.Type)
+	}
+
+	return config, backupConf, maintainer
+}
+
+// addRunLockFlags adds flags of commands locking backup with acquireRunLock
+func addRunLockFlags(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&runLockWait, "wait", 0, "wait up to duration for another run of the backup to finish")
+}
+
+// acquireRunLock locks backup for operation on this host, waiting up to --wait
+// for other run to finish. Exit if backup is still run by other process.
+// Returned function releases the lock
+func acquireRunLock(ctx context.Context, config *restic.Config, backup restic.Backup, operation string) func() {
+	info := restic.RunInfo{
+		PID:        os.Getpid(),
+		Backup:     backup.Name,
+		Repository: backup.Location(),
+		Operation:  operation,
+		Started:    time.Now(),
+	}
+	lock, holder, err := restic.AcquireRunLock(ctx, config.RunLockDir(), info, runLockWait)
+	if errors.Is(err, restic.ErrAlreadyRunning) {
+		slog.Error("backup is already running",
+			"backup", backup.Name,
+			"operation", holder.Operation,
+			"pid", holder.PID,
+			"started", holder.Started.Format(time.RFC3339))
+		os.Exit(alreadyRunningExitCode)
+	}
+	if err != nil {
+		exitOnRunError(operation, err)
+	}
+
+	return func() {
+		if err := lock.Release(); err != nil {
+			slog.Warn(operation+": release run lock", "error", err)
+		}
+	}
+}
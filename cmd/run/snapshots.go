@@ -6,10 +6,10 @@ package run
 import (
 	"errors"
 	"fmt"
-	"log"
 	"os"
 
 	conf "github.com/liuminhaw/wrestic-bkp/cmd/config"
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
 	"github.com/liuminhaw/wrestic-bkp/restic"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -38,30 +38,25 @@ var snapshotsCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		backupName := args[0]
 
-		requirementsCheck()
-
 		config, err := restic.NewConfig(viper.ConfigFileUsed())
 		if err != nil {
-			log.Fatalf("restic snapshots: %v\n", err)
+			logging.Fatal("restic snapshots", "error", err)
 		}
+		requirementsCheck(config)
 		backupConf, err := config.ReadBackup(backupName)
 		if err != nil {
 			if errors.Is(err, restic.ErrConfigBackupNameNotFound) {
 				fmt.Printf("backup %s not found in config file\n", backupName)
 				os.Exit(1)
 			}
-			log.Fatalf("restic snapshots: %v\n", err)
+			logging.Fatal("restic snapshots", "error", err)
 		}
 
-		backupRepo, err := config.CreateRepositoryStruct(backupConf.Config)
-		if err != nil {
-			log.Fatalf("restic snapshots: %v\n", err)
-		}
-		output, err := backupRepo.Snapshots()
+		backupRepo := backupConf.Repository()
+		output, err := backupRepo.Snapshots(cmd.Context())
 		if err != nil {
 			fmt.Print(string(output))
-			fmt.Printf("restic snapshots: %v\n", err)
-			os.Exit(1)
+			exitOnRunError("restic snapshots", err)
 		}
 		fmt.Println(string(output))
 	},
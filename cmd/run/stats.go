@@ -0,0 +1,205 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package run
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Output formats of stats command
+const (
+	statsFormatTable string = "table"
+	statsFormatJSON  string = "json"
+	statsFormatCSV   string = "csv"
+)
+
+var (
+	statsOptions restic.StatsOptions
+	statsTrend   bool
+	statsFormat  string
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats BackupName",
+	Short: "Show size of repository",
+	Long: `Show size of snapshots counted by --mode:
+  restore-size       size of files when restored (default)
+  raw-data           size of deduplicated and compressed data stored in repository
+  files-by-contents  size of files with unique contents
+With --trend, show data added and restore size per day from snapshot summaries
+(snapshots taken by restic 0.17 or newer). Output is a table, json or csv`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := statsOptions.Validate(); err != nil {
+			return err
+		}
+		switch statsFormat {
+		case statsFormatTable, statsFormatJSON, statsFormatCSV:
+		default:
+			return fmt.Errorf("format should be %s, %s or %s: %s", statsFormatTable, statsFormatJSON, statsFormatCSV, statsFormat)
+		}
+		if statsTrend && (statsOptions.Mode != "" || len(statsOptions.Snapshots) > 0) {
+			return errors.New("--mode and --snapshot are not used with --trend")
+		}
+
+		return validBackupArgs(1, 1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		backupName := args[0]
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			logging.Fatal("repository stats", "error", err)
+		}
+		requirementsCheck(config)
+		backupConf, err := config.ReadBackup(backupName)
+		if err != nil {
+			if errors.Is(err, restic.ErrConfigBackupNameNotFound) {
+				fmt.Printf("backup %s not found in config file\n", backupName)
+				os.Exit(1)
+			}
+			logging.Fatal("repository stats", "error", err)
+		}
+
+		statter, ok := backupConf.Repository().(restic.Statter)
+		if !ok {
+			logging.Fatal("repository stats: backup type does not support stats", "type", backupConf.Type)
+		}
+
+		if statsTrend {
+			days, err := statter.StatsTrend(cmd.Context())
+			if err != nil {
+				exitOnRunError("repository stats", err)
+			}
+			if err := printStatsTrend(days, statsFormat); err != nil {
+				logging.Fatal("repository stats", "error", err)
+			}
+			return
+		}
+
+		stats, err := statter.Stats(cmd.Context(), statsOptions)
+		if err != nil {
+			exitOnRunError("repository stats", err)
+		}
+		if err := printStats(stats, statsOptions.Mode, statsFormat); err != nil {
+			logging.Fatal("repository stats", "error", err)
+		}
+	},
+}
+
+// printStats prints stats counted in mode as format
+func printStats(stats restic.Stats, mode, format string) error {
+	if mode == "" {
+		mode = restic.StatsRestoreSize
+	}
+
+	switch format {
+	case statsFormatJSON:
+		return printJSON(stats)
+	case statsFormatCSV:
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"mode", "snapshots", "total_size", "total_file_count", "total_blob_count",
+			"total_uncompressed_size", "compression_ratio", "compression_space_saving"})
+		w.Write([]string{mode, formatUint(stats.SnapshotsCount), formatUint(stats.TotalSize),
+			formatUint(stats.TotalFileCount), formatUint(stats.TotalBlobCount), formatUint(stats.TotalUncompressedSize),
+			formatFloat(stats.CompressionRatio), formatFloat(stats.CompressionSpaceSaving)})
+		w.Flush()
+		return w.Error()
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "mode:\t%s\n", mode)
+	fmt.Fprintf(w, "snapshots:\t%d\n", stats.SnapshotsCount)
+	fmt.Fprintf(w, "total size:\t%s\n", restic.FormatBytes(stats.TotalSize))
+	if stats.TotalFileCount > 0 {
+		fmt.Fprintf(w, "total files:\t%d\n", stats.TotalFileCount)
+	}
+	if stats.TotalBlobCount > 0 {
+		fmt.Fprintf(w, "total blobs:\t%d\n", stats.TotalBlobCount)
+	}
+	if stats.TotalUncompressedSize > 0 {
+		fmt.Fprintf(w, "uncompressed size:\t%s\n", restic.FormatBytes(stats.TotalUncompressedSize))
+		fmt.Fprintf(w, "compression ratio:\t%.2fx\n", stats.CompressionRatio)
+		fmt.Fprintf(w, "compression space saving:\t%.2f%%\n", stats.CompressionSpaceSaving)
+	}
+
+	return w.Flush()
+}
+
+// printStatsTrend prints growth of repository per day as format
+func printStatsTrend(days []restic.DailyGrowth, format string) error {
+	switch format {
+	case statsFormatJSON:
+		return printJSON(days)
+	case statsFormatCSV:
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"date", "snapshots", "data_added", "data_added_packed", "restore_size", "unsummarized"})
+		for _, day := range days {
+			w.Write([]string{day.Date, strconv.Itoa(day.Snapshots), formatUint(day.DataAdded),
+				formatUint(day.DataAddedPacked), formatUint(day.RestoreSize), strconv.Itoa(day.Unsummarized)})
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	if len(days) == 0 {
+		fmt.Println("no snapshots in repository")
+		return nil
+	}
+
+	unsummarized := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DATE\tSNAPSHOTS\tDATA ADDED\tSTORED\tRESTORE SIZE")
+	for _, day := range days {
+		added, stored := restic.FormatBytes(day.DataAdded), restic.FormatBytes(day.DataAddedPacked)
+		if day.Unsummarized == day.Snapshots {
+			added, stored = "-", "-"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", day.Date, day.Snapshots, added, stored, restic.FormatBytes(day.RestoreSize))
+		unsummarized += day.Unsummarized
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if unsummarized > 0 {
+		fmt.Printf("\n%d snapshots taken by restic older than 0.17 have no summary, their added data is not counted\n", unsummarized)
+	}
+
+	return nil
+}
+
+// printJSON prints v as indented json
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
+
+func formatUint(n uint64) string {
+	return strconv.FormatUint(n, 10)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func init() {
+	RunCmd.AddCommand(statsCmd)
+	statsCmd.Flags().StringVar(&statsOptions.Mode, "mode", "", "counting mode: restore-size, raw-data or files-by-contents (default restore-size)")
+	statsCmd.Flags().StringSliceVar(&statsOptions.Snapshots, "snapshot", nil, "count only snapshot ID or \"latest\", can be repeated")
+	statsCmd.Flags().BoolVar(&statsTrend, "trend", false, "show repository growth per day")
+	statsCmd.Flags().StringVar(&statsFormat, "format", statsFormatTable, "output format: table, json or csv")
+}
@@ -0,0 +1,110 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package run
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	tagOptions restic.TagOptions
+	tagApply   bool
+)
+
+// tagCmd represents the tag command
+var tagCmd = &cobra.Command{
+	Use:   "tag BackupName",
+	Short: "Add, remove or set tags of snapshots",
+	Long: `Add and remove tags of snapshots, or replace their tags with --set.
+All snapshots are changed unless --snapshot is given. Changes are only shown
+until --apply is given. Changed snapshots are saved with new IDs`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := tagOptions.Validate(); err != nil {
+			return err
+		}
+
+		return validBackupArgs(1, 1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		backupName := args[0]
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			logging.Fatal("repository tag", "error", err)
+		}
+		requirementsCheck(config)
+		backupConf, err := config.ReadBackup(backupName)
+		if err != nil {
+			if errors.Is(err, restic.ErrConfigBackupNameNotFound) {
+				fmt.Printf("backup %s not found in config file\n", backupName)
+				os.Exit(1)
+			}
+			logging.Fatal("repository tag", "error", err)
+		}
+
+		repo := backupConf.Repository()
+		browser, ok := repo.(restic.Browser)
+		tagger, tagOk := repo.(restic.Tagger)
+		if !ok || !tagOk {
+			logging.Fatal("repository tag: backup type does not support tag", "type", backupConf.Type)
+		}
+
+		if tagApply {
+			release := acquireRunLock(cmd.Context(), config, backupConf, restic.OperationTag)
+			defer release()
+		}
+
+		opts := tagOptions
+		opts.Snapshots = resolveSnapshots(cmd.Context(), "repository tag", browser, opts.Snapshots...)
+		changes, err := tagger.Tag(cmd.Context(), opts, tagApply)
+		if err != nil {
+			exitOnRunError("repository tag", err)
+		}
+		if len(changes) == 0 {
+			fmt.Println("no snapshot tags changed")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SNAPSHOT\tTAGS\tNEW TAGS")
+		for _, change := range changes {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", shortSnapshotID(change.Snapshot), formatTags(change.Before), formatTags(change.After))
+		}
+		w.Flush()
+
+		if tagApply {
+			fmt.Printf("\ntags of %d snapshots changed\n", len(changes))
+		} else {
+			fmt.Printf("\ndry run: tags of %d snapshots would change, run with --apply to change them\n", len(changes))
+		}
+	},
+}
+
+// formatTags joins tags with comma, "-" if there is none
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return "-"
+	}
+
+	return strings.Join(tags, ",")
+}
+
+func init() {
+	RunCmd.AddCommand(tagCmd)
+	addRunLockFlags(tagCmd)
+	tagCmd.Flags().StringSliceVar(&tagOptions.Snapshots, "snapshot", nil, "change only snapshot ID or \"latest\", can be repeated")
+	tagCmd.Flags().StringSliceVar(&tagOptions.Add, "add", nil, "add tag, can be repeated")
+	tagCmd.Flags().StringSliceVar(&tagOptions.Remove, "remove", nil, "remove tag, can be repeated")
+	tagCmd.Flags().StringSliceVar(&tagOptions.Set, "set", nil, "replace all tags with tag, can be repeated")
+	tagCmd.Flags().BoolVar(&tagApply, "apply", false, "change snapshot tags instead of only showing changes")
+}
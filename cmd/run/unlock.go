@@ -0,0 +1,84 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package run
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	conf "github.com/liuminhaw/wrestic-bkp/cmd/config"
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var unlockRemoveAll bool
+
+// unlockCmd represents the unlock command
+var unlockCmd = &cobra.Command{
+	Use:   "unlock BackupName",
+	Short: "Remove stale locks from repository of BackupName",
+	Long: `Remove locks left by interrupted restic runs from repository of BackupName.
+With --remove-all, locks of running restic processes are removed as well`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		if !config.IsValidName(args[0]) {
+			return fmt.Errorf("given name '%s' not found in config names: %v", args[0], conf.ValidConfigNames(config))
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		backupName := args[0]
+
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			logging.Fatal("repository unlock", "error", err)
+		}
+		requirementsCheck(config)
+		backupConf, err := config.ReadBackup(backupName)
+		if err != nil {
+			if errors.Is(err, restic.ErrConfigBackupNameNotFound) {
+				fmt.Printf("backup %s not found in config file\n", backupName)
+				os.Exit(1)
+			}
+			logging.Fatal("repository unlock", "error", err)
+		}
+
+		unlocker, ok := backupConf.Repository().(restic.Unlocker)
+		if !ok {
+			logging.Fatal("repository unlock: backup type does not support unlock", "type", backupConf.Type)
+		}
+
+		locks, err := unlocker.Locks(cmd.Context())
+		if err != nil {
+			exitOnRunError("repository unlock", err)
+		}
+		for _, lock := range locks {
+			fmt.Println(lock)
+		}
+
+		output, err := unlocker.Unlock(cmd.Context(), unlockRemoveAll)
+		if err != nil {
+			fmt.Print(string(output))
+			exitOnRunError("repository unlock", err)
+		}
+		fmt.Print(string(output))
+	},
+}
+
+func init() {
+	RunCmd.AddCommand(unlockCmd)
+
+	unlockCmd.Flags().BoolVar(&unlockRemoveAll, "remove-all", false, "remove all locks, even those of running restic processes")
+}
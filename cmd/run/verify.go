@@ -0,0 +1,131 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var verifyOptions restic.VerifyOptions
+
+// VerifyCmd represents the verify command, registered at top level
+var VerifyCmd = &cobra.Command{
+	Use:   "verify BackupName",
+	Short: "Restore sample of files from snapshot and verify them",
+	Long: `Restore random files (default from verify config of backup), or files under --path,
+from latest snapshot into a temporary directory. Restic verifies restored contents
+against hashes in repository, sizes are checked against snapshot, and with
+--compare-source contents are compared with source files unchanged since snapshot.
+Temporary directory is removed afterwards. Exit with status 1 if any file fails`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if verifyOptions.Files < 0 {
+			return fmt.Errorf("files should not be negative: %d", verifyOptions.Files)
+		}
+
+		return validBackupArgs(1, 1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := restic.NewConfig(viper.ConfigFileUsed())
+		if err != nil {
+			logging.Fatal("verify", "error", err)
+		}
+		requirementsCheck(config)
+		backupConf, err := config.ReadBackup(args[0])
+		if err != nil {
+			logging.Fatal("verify", "error", err)
+		}
+
+		release := acquireRunLock(cmd.Context(), config, backupConf, restic.OperationVerify)
+		defer release()
+
+		opts := backupConf.Verify.Options()
+		opts.Snapshot = verifyOptions.Snapshot
+		if cmd.Flags().Changed("files") || cmd.Flags().Changed("path") {
+			opts.Files, opts.Paths = verifyOptions.Files, verifyOptions.Paths
+		}
+		if cmd.Flags().Changed("compare-source") {
+			opts.CompareSource = verifyOptions.CompareSource
+		}
+
+		err = verifyBackup(cmd.Context(), config, backupConf, opts)
+		if errors.Is(err, restic.ErrVerifyFailed) {
+			release()
+			os.Exit(1)
+		}
+		if err != nil {
+			exitOnRunError("verify", err)
+		}
+	},
+}
+
+// verifyBackup runs restore drill of backup with opts, prints verification of
+// each restored file and records time of successful drill. Return error wrapping
+// restic.ErrVerifyFailed if any file fails verification. Caller holds run lock
+func verifyBackup(ctx context.Context, config *restic.Config, backup restic.Backup, opts restic.VerifyOptions) error {
+	verifier, ok := backup.Repository().(restic.Verifier)
+	if !ok {
+		return fmt.Errorf("backup type %s does not support verify", backup.Type)
+	}
+
+	result, err := verifier.Verify(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if len(result.Files) == 0 {
+		fmt.Printf("no files to verify in snapshot %s\n", result.Snapshot)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if opts.CompareSource {
+		fmt.Fprintln(w, "STATUS\tSOURCE\tSIZE\tPATH")
+	} else {
+		fmt.Fprintln(w, "STATUS\tSIZE\tPATH")
+	}
+	for _, file := range result.Files {
+		size := restic.FormatBytes(file.Size)
+		if file.Status == restic.VerifyNotInSnapshot {
+			size = "-"
+		}
+		if opts.CompareSource {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", file.Status, file.Source, size, file.Path)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", file.Status, size, file.Path)
+		}
+	}
+	w.Flush()
+
+	failed := result.Failed()
+	fmt.Printf("\nverified %d files of snapshot %s: %d failed\n", len(result.Files), result.Snapshot, failed)
+	slog.Info("restore drill finished", "backup", backup.Name, "snapshot", result.Snapshot,
+		"files", len(result.Files), "failed", failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files: %w", failed, len(result.Files), restic.ErrVerifyFailed)
+	}
+
+	if err := restic.RecordVerify(config.CheckStateDir(), backup.Name, time.Now()); err != nil {
+		slog.Warn("verify", "error", err)
+	}
+
+	return nil
+}
+
+func init() {
+	addRunLockFlags(VerifyCmd)
+	VerifyCmd.Flags().StringVar(&verifyOptions.Snapshot, "snapshot", "", "verify snapshot ID or \"latest~N\" instead of latest snapshot")
+	VerifyCmd.Flags().IntVar(&verifyOptions.Files, "files", 0, "number of random files restored (default files of verify config or 10)")
+	VerifyCmd.Flags().StringSliceVar(&verifyOptions.Paths, "path", nil, "restore files under path instead of random files, can be repeated")
+	VerifyCmd.Flags().BoolVar(&verifyOptions.CompareSource, "compare-source", false, "compare restored files with source files unchanged since snapshot")
+}
@@ -6,10 +6,10 @@ package test
 import (
 	"errors"
 	"fmt"
-	"log"
 	"os"
 
 	conf "github.com/liuminhaw/wrestic-bkp/cmd/config"
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
 	"github.com/liuminhaw/wrestic-bkp/restic"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -43,7 +43,7 @@ var cleanCmd = &cobra.Command{
 
 		config, err := restic.NewConfig(viper.ConfigFileUsed())
 		if err != nil {
-			log.Fatalf("test clean: load config: %v\n", err)
+			logging.Fatal("test clean: load config", "error", err)
 		}
 		backupConf, err := config.ReadBackup(backupName)
 		if err != nil {
@@ -51,15 +51,15 @@ var cleanCmd = &cobra.Command{
 				fmt.Printf("backup %s not found in config file\n", backupName)
 				os.Exit(1)
 			}
-			log.Fatalf("test clean: read backupName: %v\n", err)
+			logging.Fatal("test clean: read backupName", "error", err)
 		}
 
-		backupTest, err := config.CreateTestStruct(backupConf.Config)
+		backupTest, err := backupConf.Test()
 		if err != nil {
-			log.Fatalf("test generate: create repo struct: %v\n", err)
+			logging.Fatal("test clean", "error", err)
 		}
 		if err := backupTest.TestClean(); err != nil {
-			log.Fatalf("test clean: %v\n", err)
+			logging.Fatal("test clean", "error", err)
 		}
 	},
 }
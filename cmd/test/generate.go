@@ -6,10 +6,10 @@ package test
 import (
 	"errors"
 	"fmt"
-	"log"
 	"os"
 
 	conf "github.com/liuminhaw/wrestic-bkp/cmd/config"
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
 	"github.com/liuminhaw/wrestic-bkp/restic"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -43,7 +43,7 @@ source files and destination directory for testing`,
 
 		config, err := restic.NewConfig(viper.ConfigFileUsed())
 		if err != nil {
-			log.Fatalf("test generate: load config: %v\n", err)
+			logging.Fatal("test generate: load config", "error", err)
 		}
 		backupConf, err := config.ReadBackup(backupName)
 		if err != nil {
@@ -51,15 +51,15 @@ source files and destination directory for testing`,
 				fmt.Printf("backup %s not found in config file\n", backupName)
 				os.Exit(1)
 			}
-			log.Fatalf("test generate: read backupName: %v\n", err)
+			logging.Fatal("test generate: read backupName", "error", err)
 		}
 
-		backupTest, err := config.CreateTestStruct(backupConf.Config)
+		backupTest, err := backupConf.Test()
 		if err != nil {
-			log.Fatalf("test generate: create repo struct: %v\n", err)
+			logging.Fatal("test generate", "error", err)
 		}
 		if err := backupTest.TestGenerate(); err != nil {
-			log.Fatalf("test generate: %v\n", err)
+			logging.Fatal("test generate", "error", err)
 		}
 	},
 }
@@ -4,8 +4,7 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package test
 
 import (
-	"log"
-
+	"github.com/liuminhaw/wrestic-bkp/cmd/logging"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -40,6 +39,6 @@ func init() {
 func readConfig() {
 	viper.SetConfigFile(testConfig)
 	if err := viper.ReadInConfig(); err != nil {
-		log.Fatalf("Cannot read test config file: %s\n", testConfig)
+		logging.Fatal("cannot read test config file", "file", testConfig, "error", err)
 	}
 }
@@ -0,0 +1,78 @@
+package restic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	azureAccountNameEnv    string = "AZURE_ACCOUNT_NAME"
+	azureAccountKeyEnv     string = "AZURE_ACCOUNT_KEY"
+	azureAccountSasEnv     string = "AZURE_ACCOUNT_SAS"
+	azureEndpointSuffixEnv string = "AZURE_ENDPOINT_SUFFIX"
+)
+
+func init() {
+	RegisterBackend("azure", NewBackendFactory(func() BackendConfig { return &AzureBackupConfig{} }))
+}
+
+type AzureBackupConfig struct {
+	AccountName    string `yaml:"accountName"`
+	AccountKey     string `yaml:"accountKey"`
+	AccountSas     string `yaml:"accountSas"`
+	EndpointSuffix string `yaml:"endpointSuffix"`
+	Container      string `yaml:"container"`
+	BackupSource   `yaml:",inline"`
+	Destination    string `yaml:"destination"`
+}
+
+func (c AzureBackupConfig) Validate() error {
+	if c.AccountName == "" {
+		return errors.New("azure config: accountName is required")
+	}
+	if c.AccountKey == "" && c.AccountSas == "" {
+		return errors.New("azure config: one of accountKey or accountSas is required")
+	}
+	if c.Container == "" {
+		return errors.New("azure config: container is required")
+	}
+
+	return nil
+}
+
+func (c AzureBackupConfig) String() string {
+	var builder strings.Builder
+	builder.WriteString(srcDestString(c.Sources, c.Destination))
+	builder.WriteString(fmt.Sprintf("Container: %s\n", c.Container))
+	builder.WriteString(fmt.Sprintf("Account Name: %s\n", c.AccountName))
+	builder.WriteString(fmt.Sprintf("Account Key: %s\n", c.AccountKey))
+	builder.WriteString(fmt.Sprintf("Account SAS: %s\n", c.AccountSas))
+
+	return builder.String()
+}
+
+// Backend returns azure repository in format azure:container:/path
+func (c AzureBackupConfig) Backend() Backend {
+	return Backend{
+		Type:       "azure",
+		Repository: fmt.Sprintf("azure:%s:/%s", c.Container, strings.TrimPrefix(c.Destination, "/")),
+		Envs:       c.credentialEnvs(),
+	}
+}
+
+// credentialEnvs returns account name with either account key or SAS token,
+// account key takes precedence if both are set
+func (c AzureBackupConfig) credentialEnvs() []string {
+	envs := []string{envPair(azureAccountNameEnv, c.AccountName)}
+	if c.AccountKey != "" {
+		envs = append(envs, envPair(azureAccountKeyEnv, c.AccountKey))
+	} else {
+		envs = append(envs, envPair(azureAccountSasEnv, c.AccountSas))
+	}
+	if c.EndpointSuffix != "" {
+		envs = append(envs, envPair(azureEndpointSuffixEnv, c.EndpointSuffix))
+	}
+
+	return envs
+}
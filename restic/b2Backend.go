@@ -0,0 +1,57 @@
+package restic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	b2AccountIdEnv  string = "B2_ACCOUNT_ID"
+	b2AccountKeyEnv string = "B2_ACCOUNT_KEY"
+)
+
+func init() {
+	RegisterBackend("b2", NewBackendFactory(func() BackendConfig { return &B2BackupConfig{} }))
+}
+
+type B2BackupConfig struct {
+	AccountId    string `yaml:"accountId"`
+	AccountKey   string `yaml:"accountKey"`
+	Bucket       string `yaml:"bucket"`
+	BackupSource `yaml:",inline"`
+	Destination  string `yaml:"destination"`
+}
+
+func (c B2BackupConfig) Validate() error {
+	if c.AccountId == "" || c.AccountKey == "" {
+		return errors.New("b2 config: accountId and accountKey are required")
+	}
+	if c.Bucket == "" {
+		return errors.New("b2 config: bucket is required")
+	}
+
+	return nil
+}
+
+func (c B2BackupConfig) String() string {
+	var builder strings.Builder
+	builder.WriteString(srcDestString(c.Sources, c.Destination))
+	builder.WriteString(fmt.Sprintf("Bucket: %s\n", c.Bucket))
+	builder.WriteString(fmt.Sprintf("Account ID: %s\n", c.AccountId))
+	builder.WriteString(fmt.Sprintf("Account Key: %s\n", c.AccountKey))
+
+	return builder.String()
+}
+
+// Backend returns b2 repository in format b2:bucket:path
+func (c B2BackupConfig) Backend() Backend {
+	return Backend{
+		Type:       "b2",
+		Repository: fmt.Sprintf("b2:%s:%s", c.Bucket, c.Destination),
+		Envs: []string{
+			envPair(b2AccountIdEnv, c.AccountId),
+			envPair(b2AccountKeyEnv, c.AccountKey),
+		},
+	}
+}
@@ -0,0 +1,191 @@
+package restic
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Backend describes how restic reaches the repository of a backup type
+type Backend struct {
+	// Type is the backup type name used in config file, e.g. "local"
+	Type string
+	// Repository is the value given to restic "-r" option
+	Repository string
+	// Envs are "KEY=value" environment entries set for restic invocation only
+	Envs []string
+	// Options are restic extended options, each given with "-o"
+	Options []string
+	// Preflight runs before every restic operation if set
+	Preflight func() error
+}
+
+// BackupRepository runs restic operations against repository described by Backend
+type BackupRepository struct {
+	Password string
+	// PasswordFile and PasswordCommand are used by restic instead of Password if set
+	PasswordFile    string
+	PasswordCommand string
+	Source          BackupSource
+	Backend         Backend
+	Run             RunSettings
+	// Runner runs restic commands, DefaultRunner is used if nil
+	Runner Runner
+	// Logger logs restic runs, slog default logger is used if nil
+	Logger *slog.Logger
+}
+
+func (r BackupRepository) Init(ctx context.Context) ([]byte, error) {
+	if err := r.preflight(); err != nil {
+		return nil, fmt.Errorf("%s repository init: %w", r.Backend.Type, err)
+	}
+
+	var output []byte
+	err := runOperation(ctx, r.logger(), r.Run, OperationInit, func(ctx context.Context) error {
+		var err error
+		output, err = r.execOutput(ctx, r.commandArgs("init"))
+		return err
+	})
+	if err != nil {
+		return output, fmt.Errorf("%s repository init: %w", r.Backend.Type, err)
+	}
+
+	return output, nil
+}
+
+// Backup backs up sources, returning summary of the backup reported by restic.
+// Repository is not checked, see CheckSettings.Due
+func (r BackupRepository) Backup(ctx context.Context) (BackupSummary, error) {
+	if err := r.preflight(); err != nil {
+		return BackupSummary{}, fmt.Errorf("%s repository backup: %w", r.Backend.Type, err)
+	}
+	if err := r.Source.Validate(); err != nil {
+		return BackupSummary{}, fmt.Errorf("%s repository backup: %w", r.Backend.Type, err)
+	}
+	r.unlockStale(ctx)
+
+	commandArg := append(r.commandArgs("backup"), r.Source.args()...)
+
+	var progress *backupProgress
+	err := runOperation(ctx, r.logger(), r.Run, OperationBackup, func(ctx context.Context) error {
+		progress = &backupProgress{logger: r.logger()}
+		defer progress.progress.done()
+		return r.execJSON(ctx, commandArg, progress.handle)
+	})
+	if err != nil {
+		return BackupSummary{}, fmt.Errorf("%s repository backup: %w", r.Backend.Type, err)
+	}
+	if progress.summary == nil {
+		return BackupSummary{}, fmt.Errorf("%s repository backup: %w", r.Backend.Type, ErrNoBackupSummary)
+	}
+
+	return *progress.summary, nil
+}
+
+func (r BackupRepository) Snapshots(ctx context.Context) ([]byte, error) {
+	if err := r.preflight(); err != nil {
+		return nil, fmt.Errorf("%s repository snapshots: %w", r.Backend.Type, err)
+	}
+	r.unlockStale(ctx)
+
+	var output []byte
+	err := runOperation(ctx, r.logger(), r.Run, OperationSnapshots, func(ctx context.Context) error {
+		var err error
+		output, err = r.execOutput(ctx, r.commandArgs("snapshots"))
+		return err
+	})
+	if err != nil {
+		return output, fmt.Errorf("%s repository snapshots: %w", r.Backend.Type, err)
+	}
+
+	return output, nil
+}
+
+// Check checks repository integrity and consistency, reading pack data
+// subset set in opts
+func (r BackupRepository) Check(ctx context.Context, opts CheckOptions) error {
+	if err := r.preflight(); err != nil {
+		return fmt.Errorf("%s repository check: %w", r.Backend.Type, err)
+	}
+	r.unlockStale(ctx)
+
+	err := runOperation(ctx, r.logger(), r.Run, OperationCheck, func(ctx context.Context) error {
+		return r.execStream(ctx, append(r.commandArgs("check"), opts.args()...))
+	})
+	if err != nil {
+		return fmt.Errorf("%s repository check: %w", r.Backend.Type, err)
+	}
+
+	return nil
+}
+
+// Location returns repository given to restic "-r" option
+func (r BackupRepository) Location() string {
+	return r.Backend.Repository
+}
+
+func (r BackupRepository) logger() *slog.Logger {
+	if r.Logger == nil {
+		return slog.Default()
+	}
+
+	return r.Logger
+}
+
+func (r BackupRepository) runner() Runner {
+	if r.Runner == nil {
+		return DefaultRunner
+	}
+
+	return r.Runner
+}
+
+func (r BackupRepository) preflight() error {
+	if r.Backend.Preflight == nil {
+		return nil
+	}
+
+	return r.Backend.Preflight()
+}
+
+// commandArgs returns restic arguments for command with repository and
+// backend extended options set
+func (r BackupRepository) commandArgs(command string) []string {
+	commandArg := []string{command, "-r", r.Backend.Repository}
+	for _, option := range r.Backend.Options {
+		commandArg = append(commandArg, "-o", option)
+	}
+
+	return commandArg
+}
+
+// passwordEnvs returns restic environment of password, password file or
+// password command of repository, with prefix after "RESTIC_" in its name
+func (r BackupRepository) passwordEnvs(prefix string) []string {
+	name := func(env string) string {
+		return strings.Replace(env, "RESTIC_", "RESTIC_"+prefix, 1)
+	}
+
+	switch {
+	case r.Password != "":
+		return []string{envPair(name(passwordEnv), r.Password)}
+	case r.PasswordFile != "":
+		return []string{envPair(name(passwordFileEnv), r.PasswordFile)}
+	case r.PasswordCommand != "":
+		return []string{envPair(name(passwordCommandEnv), r.PasswordCommand)}
+	}
+
+	return []string{}
+}
+
+// envs returns repository password if set, backend environments and
+// progress rate if stdout is terminal
+func (r BackupRepository) envs() []string {
+	envs := r.passwordEnvs("")
+	if stdoutTerminal {
+		envs = append(envs, envPair(resticProgressFPS, resticProgressFPSValue))
+	}
+
+	return append(envs, r.Backend.Envs...)
+}
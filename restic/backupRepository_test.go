@@ -0,0 +1,462 @@
+package restic_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/liuminhaw/wrestic-bkp/restic/resticfake"
+)
+
+// anyArg matches any single argument of wantCall, e.g. temporary file names
+const anyArg string = "<any>"
+
+const (
+	testRepository string = "sftp:user@host:/srv/restic"
+	testOption     string = "sftp.connections=2"
+)
+
+var (
+	testBackendEnv = []string{"SSH_AUTH_SOCK=/run/agent.sock"}
+	testEnv        = []string{"RESTIC_PASSWORD=secret", "SSH_AUTH_SOCK=/run/agent.sock"}
+	// repoArgs are repository and extended option arguments following restic command
+	repoArgs = []string{"-r", testRepository, "-o", testOption}
+)
+
+// fakeResponses are scripted restic responses by restic command, e.g. "snapshots"
+var fakeResponses = map[string]resticfake.Response{
+	"version":   {Stdout: "restic 0.17.3 compiled with go1.22.5 on linux/amd64\n"},
+	"backup":    {Stdout: `{"message_type":"summary","snapshot_id":"1234abcd"}` + "\n"},
+	"snapshots": {Stdout: `[{"id":"aaaa1111","time":"2024-05-01T10:00:00Z","tags":["daily"]},{"id":"bbbb2222","time":"2024-05-02T10:00:00Z"}]`},
+	"ls":        {Stdout: `{"struct_type":"node","name":"a.txt","type":"file","path":"/src/a.txt","size":3}` + "\n"},
+	"find":      {Stdout: "[]"},
+	"key":       {Stdout: "[]"},
+	"stats":     {Stdout: `{"total_size":1024,"snapshots_count":2}`},
+}
+
+// wantCall is an expected restic invocation, env of nil is testEnv
+type wantCall struct {
+	args []string
+	env  []string
+}
+
+func command(name string, args ...string) []string {
+	commandArgs := append([]string{name}, repoArgs...)
+	return append(commandArgs, args...)
+}
+
+func testRepo(runner restic.Runner) restic.BackupRepository {
+	return restic.BackupRepository{
+		Password: "secret",
+		Source: restic.BackupSource{
+			Sources:  []string{"/src"},
+			Excludes: []string{"*.tmp"},
+			BackupOptions: restic.BackupOptions{
+				ExcludeCaches:     true,
+				ExcludeLargerThan: "1G",
+				OneFileSystem:     true,
+			},
+		},
+		Backend: restic.Backend{
+			Type:       "sftp",
+			Repository: testRepository,
+			Envs:       testBackendEnv,
+			Options:    []string{testOption},
+		},
+		Runner: runner,
+		Logger: discardLogger,
+	}
+}
+
+func TestBackupRepositoryCommands(t *testing.T) {
+	target := restic.BackupRepository{
+		Password: "target-secret",
+		Backend: restic.Backend{
+			Type:       "local",
+			Repository: "/mnt/copy",
+			Envs:       []string{"SSH_AUTH_SOCK=/run/agent.sock"},
+		},
+	}
+	copyEnv := []string{"RESTIC_PASSWORD=target-secret", "SSH_AUTH_SOCK=/run/agent.sock", "RESTIC_FROM_PASSWORD=secret"}
+	copyArgs := []string{"-r", "/mnt/copy", "-o", testOption, "--from-repo", testRepository}
+	versionCall := wantCall{args: []string{"version"}, env: []string{}}
+
+	tests := []struct {
+		name      string
+		repo      func(repo restic.BackupRepository) restic.BackupRepository
+		responses map[string]resticfake.Response
+		run       func(ctx context.Context, repo restic.BackupRepository) error
+		want      []wantCall
+	}{
+		{
+			name: "init",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				_, err := repo.Init(ctx)
+				return err
+			},
+			want: []wantCall{{args: command("init")}},
+		},
+		{
+			name: "init with password file",
+			repo: func(repo restic.BackupRepository) restic.BackupRepository {
+				repo.Password, repo.PasswordFile = "", "/etc/restic/password"
+				return repo
+			},
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				_, err := repo.Init(ctx)
+				return err
+			},
+			want: []wantCall{{
+				args: command("init"),
+				env:  []string{"RESTIC_PASSWORD_FILE=/etc/restic/password", "SSH_AUTH_SOCK=/run/agent.sock"},
+			}},
+		},
+		{
+			name: "init with password command",
+			repo: func(repo restic.BackupRepository) restic.BackupRepository {
+				repo.Password, repo.PasswordCommand = "", "pass show restic"
+				return repo
+			},
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				_, err := repo.Init(ctx)
+				return err
+			},
+			want: []wantCall{{
+				args: command("init"),
+				env:  []string{"RESTIC_PASSWORD_COMMAND=pass show restic", "SSH_AUTH_SOCK=/run/agent.sock"},
+			}},
+		},
+		{
+			name: "backup",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				_, err := repo.Backup(ctx)
+				return err
+			},
+			want: []wantCall{{args: command("backup", "/src", "--exclude=*.tmp", "--exclude-caches",
+				"--exclude-larger-than=1G", "--one-file-system", "--json")}},
+		},
+		{
+			name: "snapshots",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				_, err := repo.Snapshots(ctx)
+				return err
+			},
+			want: []wantCall{{args: command("snapshots")}},
+		},
+		{
+			name: "check",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				return repo.Check(ctx, restic.CheckOptions{ReadDataSubset: "2/10"})
+			},
+			want: []wantCall{{args: command("check", "--read-data-subset=2/10")}},
+		},
+		{
+			name: "check without subset",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				return repo.Check(ctx, restic.CheckOptions{})
+			},
+			want: []wantCall{{args: command("check")}},
+		},
+		{
+			name: "ls",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				_, err := repo.Ls(ctx, "aaaa1111", restic.LsOptions{Path: "/src", Recursive: true})
+				return err
+			},
+			want: []wantCall{{args: command("ls", "aaaa1111", "/src", "--recursive", "--json")}},
+		},
+		{
+			name: "find",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				_, err := repo.Find(ctx, "*.conf", restic.FindOptions{Oldest: "2024-05-01", Newest: "2024-06-01", Paths: []string{"/etc"}})
+				return err
+			},
+			want: []wantCall{{args: command("find", "*.conf", "--json", "--oldest", "2024-05-01",
+				"--newest", "2024-06-01", "--path", "/etc")}},
+		},
+		{
+			name: "diff",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				_, err := repo.Diff(ctx, "aaaa1111", "bbbb2222")
+				return err
+			},
+			want: []wantCall{
+				{args: command("diff", "aaaa1111", "bbbb2222", "--json")},
+				{args: command("ls", "aaaa1111", "--json")},
+				{args: command("ls", "bbbb2222", "--json")},
+			},
+		},
+		{
+			name: "diff live",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				_, err := repo.DiffLive(ctx, "aaaa1111")
+				return err
+			},
+			want: []wantCall{
+				{args: command("ls", "aaaa1111", "--json")},
+				{args: command("backup", "--dry-run", "-vv", "--parent", "aaaa1111", "/src", "--exclude=*.tmp",
+					"--exclude-caches", "--exclude-larger-than=1G", "--one-file-system", "--json")},
+			},
+		},
+		{
+			name: "dump",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				return repo.Dump(ctx, "aaaa1111", "/src", restic.DumpOptions{Archive: restic.ArchiveZip}, io.Discard)
+			},
+			want: []wantCall{{args: command("dump", "aaaa1111", "/src", "--archive", "zip")}},
+		},
+		{
+			name: "copy to",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				target.Runner = repo.Runner
+				return repo.CopyTo(ctx, target, restic.CopyOptions{Snapshots: []string{"aaaa1111"}})
+			},
+			want: []wantCall{
+				versionCall,
+				{args: []string{"cat", "-r", "/mnt/copy", "config"}, env: []string{"RESTIC_PASSWORD=target-secret", "SSH_AUTH_SOCK=/run/agent.sock"}},
+				{args: append(append([]string{"copy"}, copyArgs...), "aaaa1111"), env: copyEnv},
+			},
+		},
+		{
+			name:      "copy to new repository",
+			responses: map[string]resticfake.Response{"cat": {ExitCode: 10, Stderr: "Fatal: repository does not exist"}},
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				target.Runner = repo.Runner
+				return repo.CopyTo(ctx, target, restic.CopyOptions{})
+			},
+			want: []wantCall{
+				versionCall,
+				{args: []string{"cat", "-r", "/mnt/copy", "config"}, env: []string{"RESTIC_PASSWORD=target-secret", "SSH_AUTH_SOCK=/run/agent.sock"}},
+				{args: append(append([]string{"init"}, copyArgs...), "--copy-chunker-params"), env: copyEnv},
+				{args: append([]string{"copy"}, copyArgs...), env: copyEnv},
+			},
+		},
+		{
+			name: "keys",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				_, err := repo.Keys(ctx)
+				return err
+			},
+			want: []wantCall{{args: command("key", "list", "--json")}},
+		},
+		{
+			name: "add key",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				return repo.AddKey(ctx, "new-secret", restic.KeyOptions{User: "backup", Host: "nas"})
+			},
+			want: []wantCall{{args: command("key", "add", "--user", "backup", "--host", "nas", "--new-password-file", anyArg)}},
+		},
+		{
+			name: "remove key",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				return repo.RemoveKey(ctx, "cccc3333")
+			},
+			want: []wantCall{{args: command("key", "remove", "cccc3333")}},
+		},
+		{
+			name: "change password",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				return repo.ChangePassword(ctx, "new-secret")
+			},
+			want: []wantCall{{args: command("key", "passwd", "--new-password-file", anyArg)}},
+		},
+		{
+			name: "stats",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				_, err := repo.Stats(ctx, restic.StatsOptions{Mode: restic.StatsRawData, Snapshots: []string{"aaaa1111"}})
+				return err
+			},
+			want: []wantCall{{args: command("stats", "--json", "--mode", "raw-data", "aaaa1111")}},
+		},
+		{
+			name: "tag",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				_, err := repo.Tag(ctx, restic.TagOptions{Add: []string{"keep"}, Remove: []string{"daily"}}, true)
+				return err
+			},
+			want: []wantCall{
+				{args: command("snapshots", "--json")},
+				{args: command("tag", "--add", "keep", "--remove", "daily", "aaaa1111", "bbbb2222")},
+			},
+		},
+		{
+			name: "tag dry run",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				_, err := repo.Tag(ctx, restic.TagOptions{Set: []string{"keep"}, Snapshots: []string{"aaaa"}}, false)
+				return err
+			},
+			want: []wantCall{{args: command("snapshots", "--json")}},
+		},
+		{
+			name: "rewrite dry run",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				return repo.Rewrite(ctx, restic.RewriteOptions{Excludes: []string{"*.iso"}, Snapshots: []string{"aaaa1111"}}, false)
+			},
+			want: []wantCall{versionCall, {args: command("rewrite", "--exclude=*.iso", "--dry-run", "aaaa1111")}},
+		},
+		{
+			name: "rewrite",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				return repo.Rewrite(ctx, restic.RewriteOptions{Excludes: []string{"*.iso"}, Forget: true}, true)
+			},
+			want: []wantCall{versionCall, {args: command("rewrite", "--exclude=*.iso", "--forget")}},
+		},
+		{
+			name: "prune",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				return repo.Prune(ctx, restic.PruneOptions{MaxUnused: "5%", MaxRepackSize: "10G", DryRun: true})
+			},
+			want: []wantCall{{args: command("prune", "--max-unused", "5%", "--max-repack-size", "10G", "--dry-run")}},
+		},
+		{
+			name: "repair snapshots",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				return repo.Repair(ctx, restic.RepairSnapshots, restic.RepairOptions{Forget: true})
+			},
+			want: []wantCall{versionCall, {args: command("repair", "snapshots", "--forget")}},
+		},
+		{
+			name: "repair packs",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				return repo.Repair(ctx, restic.RepairPacks, restic.RepairOptions{Packs: []string{"dddd4444"}})
+			},
+			want: []wantCall{versionCall, {args: command("repair", "packs", "dddd4444")}},
+		},
+		{
+			name: "migrate",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				return repo.Migrate(ctx, "upgrade_repo_v2")
+			},
+			want: []wantCall{{args: command("migrate", "upgrade_repo_v2")}},
+		},
+		{
+			name: "verify latest",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				_, err := repo.Verify(ctx, restic.VerifyOptions{Paths: []string{"/src/a.txt"}})
+				return err
+			},
+			want: []wantCall{
+				{args: command("snapshots", "--json")},
+				{args: command("ls", "bbbb2222", "--json")},
+				{args: command("restore", "bbbb2222", "--target", anyArg, "--verify", "--include", "/src/a.txt")},
+			},
+		},
+		{
+			name: "verify snapshot",
+			run: func(ctx context.Context, repo restic.BackupRepository) error {
+				_, err := repo.Verify(ctx, restic.VerifyOptions{Snapshot: "aaaa1111", Files: 1})
+				return err
+			},
+			want: []wantCall{
+				{args: command("ls", "aaaa1111", "--json")},
+				{args: command("restore", "aaaa1111", "--target", anyArg, "--verify", "--include", "/src/a.txt")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &resticfake.Runner{Handler: func(call resticfake.Call) resticfake.Response {
+				if response, ok := tt.responses[call.Args[0]]; ok {
+					return response
+				}
+				return fakeResponses[call.Args[0]]
+			}}
+			repo := testRepo(fake)
+			if tt.repo != nil {
+				repo = tt.repo(repo)
+			}
+
+			if err := tt.run(context.Background(), repo); err != nil {
+				t.Fatalf("%s error = %v", tt.name, err)
+			}
+			assertCalls(t, fake.Calls(), tt.want)
+		})
+	}
+}
+
+func TestBackupRepositoryMount(t *testing.T) {
+	// Mount checks fusermount is installed on linux
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "fusermount3"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	// Mount passes stdin to restic for password prompt
+	stdin, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	origStdin := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = origStdin }()
+
+	tests := []struct {
+		name string
+		opts restic.MountOptions
+		want wantCall
+	}{
+		{
+			name: "mount",
+			opts: restic.MountOptions{Paths: []string{"/src"}, Tags: []string{"daily"}, Hosts: []string{"nas"}},
+			want: wantCall{args: command("mount", anyArg, "--path", "/src", "--tag", "daily", "--host", "nas")},
+		},
+		{
+			name: "mount with password prompt",
+			opts: restic.MountOptions{PromptPassword: true},
+			want: wantCall{args: command("mount", anyArg), env: testBackendEnv},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := resticfake.New()
+			if err := testRepo(fake).Mount(context.Background(), t.TempDir(), tt.opts); err != nil {
+				t.Fatalf("Mount() error = %v", err)
+			}
+			assertCalls(t, fake.Calls(), []wantCall{tt.want})
+		})
+	}
+}
+
+// assertCalls compares recorded restic invocations with want. Progress rate
+// environment is ignored, it is only set when stdout is a terminal
+func assertCalls(t *testing.T, calls []resticfake.Call, want []wantCall) {
+	t.Helper()
+
+	if len(calls) != len(want) {
+		lines := []string{}
+		for _, call := range calls {
+			lines = append(lines, call.CommandLine())
+		}
+		t.Fatalf("restic called %d times, want %d:\n%s", len(calls), len(want), strings.Join(lines, "\n"))
+	}
+	for i, call := range calls {
+		if !matchArgs(call.Args, want[i].args) {
+			t.Errorf("call %d args = %q, want %q", i, call.Args, want[i].args)
+		}
+
+		wantEnv := want[i].env
+		if wantEnv == nil {
+			wantEnv = testEnv
+		}
+		env := slices.DeleteFunc(slices.Clone(call.Env), func(env string) bool {
+			return strings.HasPrefix(env, "RESTIC_PROGRESS_FPS=")
+		})
+		if !slices.Equal(env, wantEnv) {
+			t.Errorf("call %d (%s) env = %q, want %q", i, call.Args[0], env, wantEnv)
+		}
+	}
+}
+
+func matchArgs(args, want []string) bool {
+	return slices.EqualFunc(args, want, func(arg, want string) bool {
+		return want == anyArg || arg == want
+	})
+}
@@ -0,0 +1,215 @@
+package restic
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AfterBackup values of CheckSettings
+const (
+	CheckNever  string = "never"
+	CheckAlways string = "always"
+	CheckWeekly string = "weekly"
+)
+
+const (
+	// checkWeeklyInterval is a week less an hour, so that backup scheduled at
+	// same time each day is not delayed a day by duration of the last check
+	checkWeeklyInterval time.Duration = 7*24*time.Hour - time.Hour
+	checkStateSuffix    string        = ".check"
+)
+
+var (
+	percentSubset  = regexp.MustCompile(`^(\d+(?:\.\d+)?)%$`)
+	fractionSubset = regexp.MustCompile(`^(\d+)/(\d+)$`)
+)
+
+// CheckSettings controls repository check of a backup
+type CheckSettings struct {
+	// AfterBackup is CheckNever, CheckAlways or CheckWeekly, CheckAlways if empty
+	AfterBackup string `yaml:"afterBackup,omitempty"`
+	// ReadDataSubset is pack data read by check, as "5%" or "n/t" for group n of t
+	ReadDataSubset string `yaml:"readDataSubset,omitempty"`
+	// RotateSubset reads a different group of ReadDataSubset each day,
+	// so whole repository data is read over consecutive days
+	RotateSubset bool `yaml:"rotateSubset,omitempty"`
+}
+
+// CheckOptions are restic check options of a single check run
+type CheckOptions struct {
+	// ReadDataSubset is given to restic "--read-data-subset" option if set
+	ReadDataSubset string
+}
+
+// Validate checks that AfterBackup and ReadDataSubset values are valid
+func (s CheckSettings) Validate() error {
+	switch s.AfterBackup {
+	case "", CheckNever, CheckAlways, CheckWeekly:
+	default:
+		return fmt.Errorf("check: afterBackup should be %s, %s or %s: %s", CheckNever, CheckAlways, CheckWeekly, s.AfterBackup)
+	}
+	if s.RotateSubset && s.ReadDataSubset == "" {
+		return errors.New("check: rotateSubset requires readDataSubset")
+	}
+	if s.ReadDataSubset != "" {
+		if _, _, err := parseDataSubset(s.ReadDataSubset); err != nil {
+			return fmt.Errorf("check: %w", err)
+		}
+	}
+	if s.RotateSubset {
+		if err := rotatableSubset(s.ReadDataSubset); err != nil {
+			return fmt.Errorf("check: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Due reports whether check should run after backup at now, given time of
+// last check. Zero lastCheck means backup was never checked
+func (s CheckSettings) Due(lastCheck, now time.Time) bool {
+	switch s.AfterBackup {
+	case CheckNever:
+		return false
+	case CheckWeekly:
+		return lastCheck.IsZero() || now.Sub(lastCheck) >= checkWeeklyInterval
+	}
+
+	return true
+}
+
+// Options returns check options of run at now. With RotateSubset, data
+// subset is a group chosen by day, so consecutive days read different groups
+func (s CheckSettings) Options(now time.Time) CheckOptions {
+	if s.ReadDataSubset == "" || !s.RotateSubset {
+		return CheckOptions{ReadDataSubset: s.ReadDataSubset}
+	}
+
+	group, total, err := parseDataSubset(s.ReadDataSubset)
+	if err != nil {
+		return CheckOptions{ReadDataSubset: s.ReadDataSubset}
+	}
+	day := int(now.Unix() / int64(24*time.Hour/time.Second))
+	group = (group-1+day)%total + 1
+
+	return CheckOptions{ReadDataSubset: fmt.Sprintf("%d/%d", group, total)}
+}
+
+// args returns restic check arguments of options
+func (o CheckOptions) args() []string {
+	if o.ReadDataSubset == "" {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("--read-data-subset=%s", o.ReadDataSubset)}
+}
+
+// parseDataSubset parses subset as group and total number of groups.
+// Percentage "p%" is read as first group of 100/p groups
+func parseDataSubset(subset string) (int, int, error) {
+	if match := fractionSubset.FindStringSubmatch(subset); match != nil {
+		group, _ := strconv.Atoi(match[1])
+		total, _ := strconv.Atoi(match[2])
+		if group < 1 || total < 1 || group > total {
+			return 0, 0, fmt.Errorf("readDataSubset %s: group should be between 1 and %d", subset, total)
+		}
+		return group, total, nil
+	}
+	if match := percentSubset.FindStringSubmatch(subset); match != nil {
+		percent, _ := strconv.ParseFloat(match[1], 64)
+		if percent <= 0 || percent > 100 {
+			return 0, 0, fmt.Errorf("readDataSubset %s: percentage should be between 0 and 100", subset)
+		}
+		return 1, int(100 / percent), nil
+	}
+
+	return 0, 0, fmt.Errorf("readDataSubset %s: should be percentage \"p%%\" or group \"n/t\"", subset)
+}
+
+// rotatableSubset checks that percentage subset divides data into whole
+// groups, otherwise rotating its groups would skip part of the data
+func rotatableSubset(subset string) error {
+	match := percentSubset.FindStringSubmatch(subset)
+	if match == nil {
+		return nil
+	}
+	percent, _ := strconv.ParseFloat(match[1], 64)
+	if groups := 100 / percent; math.Abs(groups-math.Round(groups)) > 1e-9 {
+		return fmt.Errorf("readDataSubset %s: rotateSubset requires percentage dividing 100, e.g. \"10%%\", or group \"n/t\"", subset)
+	}
+
+	return nil
+}
+
+// LastCheck returns time of last successful check of backup recorded in dir,
+// zero time if backup was never checked
+func LastCheck(dir, backup string) (time.Time, error) {
+	checked, err := readStateTime(statePath(dir, backup, checkStateSuffix))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("last check: %w", err)
+	}
+
+	return checked, nil
+}
+
+// RecordCheck records checked as time of last successful check of backup in dir
+func RecordCheck(dir, backup string, checked time.Time) error {
+	if err := writeStateTime(dir, statePath(dir, backup, checkStateSuffix), checked); err != nil {
+		return fmt.Errorf("record check: %w", err)
+	}
+
+	return nil
+}
+
+// readStateTime reads time recorded in state file at path, zero time if
+// file does not exist
+func readStateTime(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+}
+
+// writeStateTime records t in state file at path in dir
+func writeStateTime(dir, path string, t time.Time) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data := []byte(t.UTC().Format(time.RFC3339) + "\n")
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+func statePath(dir, backup, suffix string) string {
+	return filepath.Join(dir, unsafeNameChars.ReplaceAllString(backup, "_")+suffix)
+}
+
+// DefaultStateDir returns directory of persistent state, /var/lib/wrestic-bkp for root,
+// $XDG_STATE_HOME/wrestic-bkp or ~/.local/state/wrestic-bkp for other users
+func DefaultStateDir() string {
+	if os.Geteuid() == 0 {
+		return "/var/lib/wrestic-bkp"
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "wrestic-bkp")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), fmt.Sprintf("wrestic-bkp-state-%d", os.Geteuid()))
+	}
+
+	return filepath.Join(home, ".local", "state", "wrestic-bkp")
+}
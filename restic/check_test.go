@@ -0,0 +1,61 @@
+package restic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckSettingsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings CheckSettings
+		wantErr  bool
+	}{
+		{"empty", CheckSettings{}, false},
+		{"percentage", CheckSettings{ReadDataSubset: "30%"}, false},
+		{"rotate group", CheckSettings{ReadDataSubset: "1/3", RotateSubset: true}, false},
+		{"rotate dividing percentage", CheckSettings{ReadDataSubset: "10%", RotateSubset: true}, false},
+		{"rotate fractional dividing percentage", CheckSettings{ReadDataSubset: "12.5%", RotateSubset: true}, false},
+		{"rotate whole data", CheckSettings{ReadDataSubset: "100%", RotateSubset: true}, false},
+		{"rotate 30%", CheckSettings{ReadDataSubset: "30%", RotateSubset: true}, true},
+		{"rotate 70%", CheckSettings{ReadDataSubset: "70%", RotateSubset: true}, true},
+		{"rotate without subset", CheckSettings{RotateSubset: true}, true},
+		{"invalid group", CheckSettings{ReadDataSubset: "4/3"}, true},
+		{"invalid percentage", CheckSettings{ReadDataSubset: "0%"}, true},
+		{"invalid afterBackup", CheckSettings{AfterBackup: "daily"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.settings.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckSettingsOptions(t *testing.T) {
+	day := 24 * time.Hour
+	epoch := time.Unix(0, 0)
+
+	tests := []struct {
+		name     string
+		settings CheckSettings
+		now      time.Time
+		want     string
+	}{
+		{"not rotated", CheckSettings{ReadDataSubset: "5%"}, epoch.Add(3 * day), "5%"},
+		{"rotated percentage first day", CheckSettings{ReadDataSubset: "25%", RotateSubset: true}, epoch, "1/4"},
+		{"rotated percentage next day", CheckSettings{ReadDataSubset: "25%", RotateSubset: true}, epoch.Add(day), "2/4"},
+		{"rotated group wraps", CheckSettings{ReadDataSubset: "2/3", RotateSubset: true}, epoch.Add(2 * day), "1/3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.settings.Options(tt.now).ReadDataSubset; got != tt.want {
+				t.Errorf("Options().ReadDataSubset = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,9 +5,20 @@ import (
 	"os/exec"
 )
 
-// ResticCheck checks if "restic" command is available in system path
-func ResticCheck() error {
-	_, err := exec.LookPath("restic")
+// resticBinaryOverride is set by SetResticBinary and takes precedence
+// over resticBinary setting in config file
+var resticBinaryOverride string
+
+// SetResticBinary sets restic program used by every config loaded afterward,
+// overriding resticBinary setting in config file
+func SetResticBinary(binary string) {
+	resticBinaryOverride = binary
+}
+
+// ResticCheck checks if restic binary is available, either as path to an
+// executable or as command name in system path
+func ResticCheck(binary string) error {
+	_, err := exec.LookPath(binary)
 	if err != nil {
 		return fmt.Errorf("restic command check: %w", err)
 	}
@@ -3,6 +3,7 @@ package restic
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"strings"
 
@@ -13,11 +14,20 @@ import (
 var (
 	ErrConfigNotFound           = errors.New("config file not found")
 	ErrConfigBackupNameNotFound = errors.New("backup name in config not found")
+	ErrBackupTestNotSupported   = errors.New("backup type does not support test")
 )
 
 type Config struct {
-	Repository ConfigRepository `yaml:"repository"`
-	Backups    []Backup         `yaml:"backups"`
+	ResticBinary string           `yaml:"resticBinary,omitempty"`
+	RuntimeDir   string           `yaml:"runtimeDir,omitempty"`
+	StateDir     string           `yaml:"stateDir,omitempty"`
+	Repository   ConfigRepository `yaml:"repository"`
+	Backups      []Backup         `yaml:"backups"`
+	Mounts       []Mount          `yaml:"mounts,omitempty"`
+
+	runner Runner
+	// errs are invalid settings not belonging to a backup, kept by NewConfigForDiagnosis
+	errs []error
 }
 
 type BackupTypeConfig interface {
@@ -25,73 +35,151 @@ type BackupTypeConfig interface {
 	String() string
 }
 
+// ConfigRepository is repository password shared by all backups, given as
+// password, file containing it or command printing it
 type ConfigRepository struct {
-	Password string `yaml:"password"`
+	Password        string `yaml:"password,omitempty"`
+	PasswordFile    string `yaml:"passwordFile,omitempty"`
+	PasswordCommand string `yaml:"passwordCommand,omitempty"`
 }
 
-type Backup struct {
-	Name   string           `yaml:"name"`
-	Type   string           `yaml:"type"`
-	Config BackupTypeConfig `yaml:"config"`
+// Validate checks that at most one password source is set
+func (r ConfigRepository) Validate() error {
+	sources := 0
+	for _, source := range []string{r.Password, r.PasswordFile, r.PasswordCommand} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return errors.New("repository: only one of password, passwordFile and passwordCommand should be set")
+	}
+
+	return nil
 }
 
-type LocalBackupConfig struct {
-	Sources     []string `yaml:"sources"`
-	Destination string   `yaml:"destination"`
-	Excludes    []string `yaml:"excludes"`
+type Backup struct {
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type"`
+	RunSettings `yaml:",inline"`
+	Config      BackupTypeConfig `yaml:"config"`
+
+	repository ResticRepository
+	test       tests.ResticTest
+	// err is why backup config is invalid, kept by NewConfigForDiagnosis
+	err error
 }
 
-func (c LocalBackupConfig) Validate() error {
-	return nil
+// Repository returns restic repository created from backup config
+func (b Backup) Repository() ResticRepository {
+	return b.repository
 }
 
-func (c LocalBackupConfig) String() string {
-	var builder strings.Builder
-	builder.WriteString(srcDestString(c.Sources, c.Destination))
+// Location returns repository location of backup, e.g. "sftp:host:/path".
+// Backup name is returned if repository does not report its location
+func (b Backup) Location() string {
+	if locator, ok := b.repository.(interface{ Location() string }); ok {
+		return locator.Location()
+	}
 
-	return builder.String()
+	return b.Name
 }
 
-type SftpBackupConfig struct {
-	Host        string   `yaml:"host"`
-	Sources     []string `yaml:"sources"`
-	Destination string   `yaml:"destination"`
-	Excludes    []string `yaml:"excludes"`
-}
+// Test returns testing struct created from backup config.
+// Return ErrBackupTestNotSupported error if backup type has no test support
+func (b Backup) Test() (tests.ResticTest, error) {
+	if b.test == nil {
+		return nil, ErrBackupTestNotSupported
+	}
 
-func (c SftpBackupConfig) Validate() error {
-	return nil
+	return b.test, nil
 }
 
-func (c SftpBackupConfig) String() string {
-	var builder strings.Builder
-	builder.WriteString(srcDestString(c.Sources, c.Destination))
-	builder.WriteString(fmt.Sprintf("Host: %s\n", c.Host))
+// BackupSource holds source settings shared by every backup type,
+// embedded inline into each type config
+type BackupSource struct {
+	Sources       []string `yaml:"sources"`
+	Excludes      []string `yaml:"excludes"`
+	BackupOptions `yaml:",inline"`
+}
 
-	return builder.String()
+// BackupOptions are restic backup options selecting files backed up from sources
+type BackupOptions struct {
+	// ExcludeFiles are files of exclude patterns, one per line
+	ExcludeFiles []string `yaml:"excludeFiles,omitempty"`
+	// IExcludes are exclude patterns matched case insensitively
+	IExcludes []string `yaml:"iexcludes,omitempty"`
+	// ExcludeIfPresent excludes directories containing file of this name, e.g. ".nobackup"
+	ExcludeIfPresent []string `yaml:"excludeIfPresent,omitempty"`
+	// ExcludeCaches excludes directories marked by CACHEDIR.TAG
+	ExcludeCaches bool `yaml:"excludeCaches,omitempty"`
+	// ExcludeLargerThan excludes files larger than size, e.g. "1G"
+	ExcludeLargerThan string `yaml:"excludeLargerThan,omitempty"`
+	// OneFileSystem does not cross file system boundaries of sources
+	OneFileSystem bool `yaml:"oneFileSystem,omitempty"`
+	// FilesFrom are files listing paths to back up, one per line
+	FilesFrom []string `yaml:"filesFrom,omitempty"`
 }
 
-type S3BackupConfig struct {
-	AccessKeyId     string   `yaml:"accessKeyId"`
-	SecretAccessKey string   `yaml:"secretAccessKey"`
-	Region          string   `yaml:"region"`
-	Sources         []string `yaml:"sources"`
-	Destination     string   `yaml:"destination"`
-	Excludes        []string `yaml:"excludes"`
+func (s BackupSource) Source() BackupSource {
+	return s
 }
 
-func (c S3BackupConfig) Validate() error {
+// Validate checks that sources are given and files referenced by options exist
+func (s BackupSource) Validate() error {
+	if len(s.Sources) == 0 && len(s.FilesFrom) == 0 {
+		return errors.New("source: sources or filesFrom should be set")
+	}
+	if s.ExcludeLargerThan != "" && !sizeLimit.MatchString(s.ExcludeLargerThan) {
+		return fmt.Errorf("source: excludeLargerThan should be size, e.g. 1G: %s", s.ExcludeLargerThan)
+	}
+	for _, name := range s.ExcludeIfPresent {
+		if name == "" {
+			return errors.New("source: excludeIfPresent should not be empty")
+		}
+	}
+	for _, file := range append(append([]string{}, s.ExcludeFiles...), s.FilesFrom...) {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("source: %w", err)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("source: %s is not a regular file", file)
+		}
+	}
+
 	return nil
 }
 
-func (c S3BackupConfig) String() string {
-	var builder strings.Builder
-	builder.WriteString(srcDestString(c.Sources, c.Destination))
-	builder.WriteString(fmt.Sprintf("Access Key ID: %s\n", c.AccessKeyId))
-	builder.WriteString(fmt.Sprintf("Secret Access Key: %s\n", c.SecretAccessKey))
-	builder.WriteString(fmt.Sprintf("Region: %s\n", c.Region))
+// args returns restic backup arguments of sources, excludes and options
+func (s BackupSource) args() []string {
+	args := append([]string{}, s.Sources...)
+	for _, exclude := range s.Excludes {
+		args = append(args, fmt.Sprintf("--exclude=%s", exclude))
+	}
+	for _, file := range s.ExcludeFiles {
+		args = append(args, fmt.Sprintf("--exclude-file=%s", file))
+	}
+	for _, exclude := range s.IExcludes {
+		args = append(args, fmt.Sprintf("--iexclude=%s", exclude))
+	}
+	for _, name := range s.ExcludeIfPresent {
+		args = append(args, fmt.Sprintf("--exclude-if-present=%s", name))
+	}
+	if s.ExcludeCaches {
+		args = append(args, "--exclude-caches")
+	}
+	if s.ExcludeLargerThan != "" {
+		args = append(args, fmt.Sprintf("--exclude-larger-than=%s", s.ExcludeLargerThan))
+	}
+	if s.OneFileSystem {
+		args = append(args, "--one-file-system")
+	}
+	for _, file := range s.FilesFrom {
+		args = append(args, fmt.Sprintf("--files-from=%s", file))
+	}
 
-	return builder.String()
+	return args
 }
 
 func srcDestString(sources []string, destination string) string {
@@ -107,20 +195,36 @@ func srcDestString(sources []string, destination string) string {
 
 // NewConfig read in file and return restic Config type struct
 func NewConfig(filepath string) (*Config, error) {
+	return loadConfig(filepath, false)
+}
+
+// NewConfigForDiagnosis reads config file like NewConfig, but keeps backups
+// with invalid config instead of failing, so 'doctor' can report them. Errors
+// of backups are reported by Backup.Diagnose and other errors by Config.Diagnose
+func NewConfigForDiagnosis(filepath string) (*Config, error) {
+	return loadConfig(filepath, true)
+}
+
+// loadConfig reads config file, failing on first invalid setting unless
+// lenient is set, in which case errors are recorded in config and its backups
+func loadConfig(filepath string, lenient bool) (*Config, error) {
 	data, err := os.ReadFile(filepath)
 	if err != nil {
 		return nil, ErrConfigNotFound
 	}
 
 	var rawConfig struct {
-		Repository struct {
-			Password string `yaml:"password"`
-		} `yaml:"repository"`
-		Backups []struct {
-			Name   string                 `yaml:"name"`
-			Type   string                 `yaml:"type"`
-			Config map[string]interface{} `yaml:"config"`
+		ResticBinary string           `yaml:"resticBinary"`
+		RuntimeDir   string           `yaml:"runtimeDir"`
+		StateDir     string           `yaml:"stateDir"`
+		Repository   ConfigRepository `yaml:"repository"`
+		Backups      []struct {
+			Name        string `yaml:"name"`
+			Type        string `yaml:"type"`
+			RunSettings `yaml:",inline"`
+			Config      yaml.Node `yaml:"config"`
 		} `yaml:"backups"`
+		Mounts []Mount `yaml:"mounts"`
 	}
 
 	err = yaml.Unmarshal(data, &rawConfig)
@@ -129,41 +233,172 @@ func NewConfig(filepath string) (*Config, error) {
 	}
 
 	// Process raw backup configuration
-	config := Config{Repository: rawConfig.Repository}
+	config := Config{
+		ResticBinary: rawConfig.ResticBinary,
+		RuntimeDir:   rawConfig.RuntimeDir,
+		StateDir:     rawConfig.StateDir,
+		Repository:   rawConfig.Repository,
+	}
+	if err := config.Repository.Validate(); err != nil {
+		if !lenient {
+			return nil, fmt.Errorf("new config: %w", err)
+		}
+		config.errs = append(config.errs, err)
+	}
+	config.runner = ExecRunner{Binary: config.Binary()}
 	for _, rawBackup := range rawConfig.Backups {
-		var typedConfig BackupTypeConfig
-
-		switch rawBackup.Type {
-		case "local":
-			typedConfig = &LocalBackupConfig{}
-		case "sftp":
-			typedConfig = &SftpBackupConfig{}
-		case "s3":
-			typedConfig = &S3BackupConfig{}
-		default:
-			return nil, fmt.Errorf("new config: unsupportedt type %s", rawBackup.Type)
+		backup := Backup{
+			Name:        rawBackup.Name,
+			Type:        rawBackup.Type,
+			RunSettings: rawBackup.RunSettings,
+		}
+		backup.err = backup.init(&rawBackup.Config, config)
+		if backup.err != nil && !lenient {
+			return nil, fmt.Errorf("new config: backup %s: %w", backup.Name, backup.err)
 		}
 
-		configBytes, err := yaml.Marshal(rawBackup.Config)
-		if err != nil {
-			return nil, fmt.Errorf("new config: remarshal: %w", err)
+		config.Backups = append(config.Backups, backup)
+	}
+
+	for i, backup := range config.Backups {
+		for _, target := range backup.CopyTo {
+			var err error
+			if target == backup.Name {
+				err = errors.New("copyTo should not be itself")
+			} else if !config.IsValidName(target) {
+				err = fmt.Errorf("copyTo %s: %w", target, ErrConfigBackupNameNotFound)
+			}
+			if err == nil {
+				continue
+			}
+			if !lenient {
+				return nil, fmt.Errorf("new config: backup %s: %w", backup.Name, err)
+			}
+			if backup.err == nil {
+				config.Backups[i].err = err
+			}
 		}
+	}
 
-		// Re-marshal config
-		err = yaml.Unmarshal(configBytes, typedConfig)
+	for _, mount := range rawConfig.Mounts {
+		var err error
+		if mount.Name == "" {
+			err = errors.New("mount name should be set")
+		} else if !config.IsValidName(mount.Backup) {
+			err = fmt.Errorf("mount %s: backup %s: %w", mount.Name, mount.Backup, ErrConfigBackupNameNotFound)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("new config: remarshal: %w", err)
+			if !lenient {
+				return nil, fmt.Errorf("new config: %w", err)
+			}
+			config.errs = append(config.errs, err)
+			continue
 		}
+		config.Mounts = append(config.Mounts, mount)
+	}
 
-		config.Backups = append(config.Backups, Backup{
-			Name:   rawBackup.Name,
-			Type:   rawBackup.Type,
-			Config: typedConfig,
-		})
+	return &config, nil
+}
 
+// init validates run settings and creates repository of backup from its
+// type config node
+func (b *Backup) init(node *yaml.Node, config Config) error {
+	factory, ok := lookupBackend(b.Type)
+	if !ok {
+		return fmt.Errorf("unsupported type %s", b.Type)
+	}
+	if err := b.RunSettings.Validate(); err != nil {
+		return err
 	}
 
-	return &config, nil
+	settings := BackupSettings{
+		Repository: config.Repository,
+		Run:        b.RunSettings,
+		Runner:     config.runner,
+		Logger:     slog.Default().With("backup", b.Name),
+	}
+	backupType, err := factory(node, settings)
+	if err != nil {
+		return err
+	}
+	b.Config = backupType.Config
+	b.repository = backupType.Repository
+	b.test = backupType.Test
+
+	return nil
+}
+
+// Binary returns restic program to run, set by SetResticBinary, resticBinary
+// config or "restic" from system path in order
+func (c *Config) Binary() string {
+	switch {
+	case resticBinaryOverride != "":
+		return resticBinaryOverride
+	case c.ResticBinary != "":
+		return c.ResticBinary
+	default:
+		return resticCmd
+	}
+}
+
+// Runner returns runner of restic program from Binary
+func (c *Config) Runner() Runner {
+	if c.runner == nil {
+		return ExecRunner{Binary: c.Binary()}
+	}
+
+	return c.runner
+}
+
+// RunLockDir returns directory of local run locks, runtimeDir config or
+// DefaultRuntimeDir if not set
+func (c *Config) RunLockDir() string {
+	if c.RuntimeDir == "" {
+		return DefaultRuntimeDir()
+	}
+
+	return c.RuntimeDir
+}
+
+// CheckStateDir returns directory recording last check of backups, stateDir
+// config or DefaultStateDir if not set
+func (c *Config) CheckStateDir() string {
+	if c.StateDir == "" {
+		return DefaultStateDir()
+	}
+
+	return c.StateDir
+}
+
+// RunLogDir returns directory of run log, kept in same directory as check state
+func (c *Config) RunLogDir() string {
+	return c.CheckStateDir()
+}
+
+// ReadMount finds Mount with given name, or mount of whole repository of backup
+// with given name if no mount is named so. Return ErrConfigMountNameNotFound
+// error if neither is found
+func (c *Config) ReadMount(name string) (Mount, error) {
+	for _, mount := range c.Mounts {
+		if mount.Name == name {
+			return mount, nil
+		}
+	}
+	if c.IsValidName(name) {
+		return Mount{Name: name, Backup: name}, nil
+	}
+
+	return Mount{}, ErrConfigMountNameNotFound
+}
+
+// MountNames returns names of mounts in config
+func (c *Config) MountNames() []string {
+	names := []string{}
+	for _, mount := range c.Mounts {
+		names = append(names, mount.Name)
+	}
+
+	return names
 }
 
 // ReadBackup find Backup struct with given name and returns it.
@@ -196,53 +431,6 @@ func (c *Config) IsValidName(name string) bool {
 	return false
 }
 
-func (c *Config) CreateRepositoryStruct(bConf BackupTypeConfig) (ResticRepository, error) {
-	switch v := bConf.(type) {
-	case *LocalBackupConfig:
-		return LocalBackupRepository{
-			Password:    c.Repository.Password,
-			Destination: v.Destination,
-			Sources:     v.Sources,
-			Excludes:    v.Excludes,
-		}, nil
-	case *S3BackupConfig:
-		return S3BackupRepository{
-			Password:        c.Repository.Password,
-			Destination:     v.Destination,
-			Sources:         v.Sources,
-			Excludes:        v.Excludes,
-			AccessKeyId:     v.AccessKeyId,
-			SecretAccessKey: v.SecretAccessKey,
-		}, nil
-	case *SftpBackupConfig:
-		return SftpBackupRepository{
-			Password:    c.Repository.Password,
-			Destination: v.Destination,
-			Sources:     v.Sources,
-			Excludes:    v.Excludes,
-			ConfigHost:  v.Host,
-		}, nil
-	default:
-		fmt.Printf("type of bConf: %T\n", v)
-		return nil, errors.New("no matched concrete type")
-	}
-}
-
-func (c *Config) CreateTestStruct(bConf BackupTypeConfig) (tests.ResticTest, error) {
-	switch v := bConf.(type) {
-	case *LocalBackupConfig:
-		return tests.LocalRepositoryTest{
-			Password:    c.Repository.Password,
-			Destination: v.Destination,
-			Sources:     v.Sources,
-		}, nil
-	default:
-		fmt.Printf("type of bConf: %T\n", v)
-		return nil, errors.New("no matched concrete type")
-	}
-
-}
-
 // type Config struct {
 // 	Repository struct {
 // 		Password string `yaml:"password"`
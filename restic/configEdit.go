@@ -0,0 +1,121 @@
+package restic
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UpdateConfigRepository rewrites password source of repository section in
+// config file at path in place, keeping comments and other settings. Password
+// sources not set in repository are removed
+func UpdateConfigRepository(path string, repository ConfigRepository) error {
+	if err := repository.Validate(); err != nil {
+		return fmt.Errorf("update config: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("update config: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("update config: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("update config: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return errors.New("update config: config should be a mapping")
+	}
+
+	repoNode := mappingValue(doc.Content[0], "repository")
+	if repoNode == nil {
+		repoNode = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(doc.Content[0], "repository", repoNode)
+	}
+	sources := []struct {
+		key   string
+		value string
+	}{
+		{"password", repository.Password},
+		{"passwordFile", repository.PasswordFile},
+		{"passwordCommand", repository.PasswordCommand},
+	}
+	for _, source := range sources {
+		if source.value == "" {
+			removeMappingValue(repoNode, source.key)
+			continue
+		}
+		setMappingValue(repoNode, source.key, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: source.value})
+	}
+
+	var buf bytes.Buffer
+	if bytes.HasPrefix(data, []byte("---")) {
+		buf.WriteString("---\n")
+	}
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("update config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("update config: %w", err)
+	}
+
+	// Replace config by rename so it is never left partially written
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("update config: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("update config: %w", err)
+	}
+
+	return nil
+}
+
+// mappingValue returns value node of key in mapping node, nil if not found
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// setMappingValue sets value of key in mapping node, keeping comments of
+// existing key and value
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			old := mapping.Content[i+1]
+			value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+			if old.Kind == yaml.ScalarNode && value.Kind == yaml.ScalarNode {
+				value.Style = old.Style
+			}
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// removeMappingValue removes key and its value from mapping node
+func removeMappingValue(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package restic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// copyEnvConflictHint explains restic limitation of a single backend environment
+// shared by source and target of copy
+const copyEnvConflictHint string = `restic copy reads backend credentials of source and target repositories from the
+same environment, so s3, b2, azure or gs repositories of different accounts cannot be
+copied between directly. Use rclone backup type for one of the repositories instead,
+its rclone remote keeps own credentials in rclone config`
+
+var (
+	ErrCopyNotSupported = errors.New("repository does not support copy")
+	ErrCopyEnvConflict  = errors.New("source and target repositories need different values of environment")
+)
+
+// CopyOptions selects snapshots copied by CopyTo
+type CopyOptions struct {
+	// Snapshots are IDs of copied snapshots, all snapshots not in target if empty
+	Snapshots []string
+}
+
+// Copier is implemented by repositories copying their snapshots to other repositories
+type Copier interface {
+	CopyTo(ctx context.Context, target ResticRepository, opts CopyOptions) error
+}
+
+// CopyTo copies snapshots to target repository without reading sources again.
+// Target repository is created with chunker parameters of r if it does not exist,
+// so data is deduplicated across both. Credentials of both repositories are given
+// in restic environment, backends sharing environment names should have same values
+func (r BackupRepository) CopyTo(ctx context.Context, target ResticRepository, opts CopyOptions) error {
+	to, ok := target.(BackupRepository)
+	if !ok {
+		return fmt.Errorf("%s repository copy: target: %w", r.Backend.Type, ErrCopyNotSupported)
+	}
+	if err := r.preflight(); err != nil {
+		return fmt.Errorf("%s repository copy: %w", r.Backend.Type, err)
+	}
+	if err := to.preflight(); err != nil {
+		return fmt.Errorf("%s repository copy: target: %w", r.Backend.Type, err)
+	}
+	if err := RequireFeature(ctx, r.runner(), FeatureCopyFromRepo); err != nil {
+		return fmt.Errorf("%s repository copy: %w", r.Backend.Type, err)
+	}
+
+	copyRepo, err := r.copyRepository(to)
+	if err != nil {
+		return fmt.Errorf("%s repository copy: %w", r.Backend.Type, err)
+	}
+	r.unlockStale(ctx)
+	to.unlockStale(ctx)
+
+	fromArgs := []string{"--from-repo", r.Backend.Repository}
+	_, err = to.execOutput(ctx, append(to.commandArgs("cat"), "config"))
+	if failureKind(err) == FailureRepoNotExist {
+		r.logger().Info("create copy target repository", "repository", to.Backend.Repository)
+		commandArg := append(copyRepo.commandArgs("init"), fromArgs...)
+		commandArg = append(commandArg, "--copy-chunker-params")
+		err := runOperation(ctx, r.logger(), r.Run, OperationInit, func(ctx context.Context) error {
+			_, err := copyRepo.execOutput(ctx, commandArg)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%s repository copy: init target: %w", r.Backend.Type, err)
+		}
+	}
+
+	commandArg := append(copyRepo.commandArgs("copy"), fromArgs...)
+	commandArg = append(commandArg, opts.Snapshots...)
+	err = runOperation(ctx, r.logger(), r.Run, OperationCopy, func(ctx context.Context) error {
+		return copyRepo.execStream(ctx, commandArg)
+	})
+	if err != nil {
+		return fmt.Errorf("%s repository copy: %w", r.Backend.Type, err)
+	}
+
+	return nil
+}
+
+// copyRepository returns target repository running restic with environments
+// and extended options of both r and target, and password of r as source password
+func (r BackupRepository) copyRepository(target BackupRepository) (BackupRepository, error) {
+	envs := map[string]string{}
+	for _, env := range target.Backend.Envs {
+		key, value, _ := strings.Cut(env, "=")
+		envs[key] = value
+	}
+
+	copyRepo := target
+	copyRepo.Logger = r.logger()
+	copyRepo.Backend.Envs = append(slices.Clone(target.Backend.Envs), r.passwordEnvs("FROM_")...)
+	for _, env := range r.Backend.Envs {
+		key, value, _ := strings.Cut(env, "=")
+		targetValue, ok := envs[key]
+		if ok && targetValue != value {
+			return BackupRepository{}, &HintError{
+				Err:  fmt.Errorf("%w %s", ErrCopyEnvConflict, key),
+				Hint: copyEnvConflictHint,
+			}
+		}
+		if !ok {
+			copyRepo.Backend.Envs = append(copyRepo.Backend.Envs, env)
+		}
+	}
+	copyRepo.Backend.Options = slices.Clone(target.Backend.Options)
+	for _, option := range r.Backend.Options {
+		if !slices.Contains(copyRepo.Backend.Options, option) {
+			copyRepo.Backend.Options = append(copyRepo.Backend.Options, option)
+		}
+	}
+
+	return copyRepo, nil
+}
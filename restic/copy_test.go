@@ -0,0 +1,33 @@
+package restic_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/liuminhaw/wrestic-bkp/restic/resticfake"
+)
+
+func TestCopyToCredentialsConflict(t *testing.T) {
+	fake := &resticfake.Runner{Handler: func(call resticfake.Call) resticfake.Response {
+		return fakeResponses[call.Args[0]]
+	}}
+	source := fakeRepository(fake, restic.RunSettings{})
+	source.Backend = restic.Backend{Type: "s3", Repository: "s3:s3.amazonaws.com/source", Envs: []string{"AWS_ACCESS_KEY_ID=source"}}
+	target := fakeRepository(fake, restic.RunSettings{})
+	target.Backend = restic.Backend{Type: "s3", Repository: "s3:s3.amazonaws.com/target", Envs: []string{"AWS_ACCESS_KEY_ID=target"}}
+
+	err := source.CopyTo(context.Background(), target, restic.CopyOptions{})
+	if !errors.Is(err, restic.ErrCopyEnvConflict) {
+		t.Fatalf("CopyTo() error = %v, want ErrCopyEnvConflict", err)
+	}
+	if restic.ErrorHint(err) == "" {
+		t.Error("CopyTo() error has no hint on working around shared credentials")
+	}
+	for _, call := range fake.Calls() {
+		if call.Args[0] == "copy" || call.Args[0] == "init" {
+			t.Errorf("restic %s run despite credentials conflict", call.Args[0])
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package restic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Diff modifiers of DiffChange, combined for changes of multiple kinds, e.g. "MU"
+const (
+	DiffAdded    string = "+"
+	DiffRemoved  string = "-"
+	DiffModified string = "M"
+	DiffType     string = "T"
+	DiffMetadata string = "U"
+)
+
+// DiffChange is a path changed between snapshots
+type DiffChange struct {
+	// Path of changed node, directories end with "/"
+	Path string
+	// Modifier is DiffAdded, DiffRemoved or combination of DiffModified,
+	// DiffType and DiffMetadata
+	Modifier string
+	// SizeDelta is change of file size in bytes
+	SizeDelta int64
+}
+
+// DiffStats counts nodes added or removed between snapshots
+type DiffStats struct {
+	Files  uint64 `json:"files"`
+	Dirs   uint64 `json:"dirs"`
+	Others uint64 `json:"others"`
+	Bytes  uint64 `json:"bytes"`
+}
+
+// DiffResult is changes from snapshot From to snapshot To. To is empty
+// if snapshot is compared with live sources
+type DiffResult struct {
+	From         string
+	To           string
+	Changes      []DiffChange
+	ChangedFiles uint64
+	Added        DiffStats
+	Removed      DiffStats
+}
+
+// Differ is implemented by repositories comparing snapshots
+type Differ interface {
+	Diff(ctx context.Context, from, to string) (DiffResult, error)
+	DiffLive(ctx context.Context, from string) (DiffResult, error)
+}
+
+// Under returns changes of paths under directory or file prefix
+func (d DiffResult) Under(prefix string) []DiffChange {
+	if prefix == "" {
+		return d.Changes
+	}
+
+	changes := []DiffChange{}
+	for _, change := range d.Changes {
+		if hasPathPrefix(change.Path, prefix) {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
+
+// Diff returns changes from snapshot from to snapshot to with file size deltas
+func (r BackupRepository) Diff(ctx context.Context, from, to string) (DiffResult, error) {
+	if err := r.preflight(); err != nil {
+		return DiffResult{}, fmt.Errorf("%s repository diff: %w", r.Backend.Type, err)
+	}
+	r.unlockStale(ctx)
+
+	var result DiffResult
+	err := runOperation(ctx, r.logger(), r.Run, OperationDiff, func(ctx context.Context) error {
+		result = DiffResult{From: from, To: to}
+		return r.execJSON(ctx, append(r.commandArgs("diff"), from, to), func(line string) {
+			var message struct {
+				MessageType  string    `json:"message_type"`
+				Path         string    `json:"path"`
+				Modifier     string    `json:"modifier"`
+				ChangedFiles uint64    `json:"changed_files"`
+				Added        DiffStats `json:"added"`
+				Removed      DiffStats `json:"removed"`
+			}
+			if err := json.Unmarshal([]byte(line), &message); err != nil {
+				r.logger().Debug("skip restic diff output", "line", line)
+				return
+			}
+			switch message.MessageType {
+			case "change":
+				result.Changes = append(result.Changes, DiffChange{Path: message.Path, Modifier: message.Modifier})
+			case "statistics":
+				result.ChangedFiles = message.ChangedFiles
+				result.Added = message.Added
+				result.Removed = message.Removed
+			}
+		})
+	})
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("%s repository diff: %w", r.Backend.Type, err)
+	}
+
+	// restic diff reports no file sizes, take them from snapshot listings
+	fromSizes, err := r.nodeSizes(ctx, from)
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("%s repository diff: %w", r.Backend.Type, err)
+	}
+	toSizes, err := r.nodeSizes(ctx, to)
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("%s repository diff: %w", r.Backend.Type, err)
+	}
+	for i, change := range result.Changes {
+		path := strings.TrimSuffix(change.Path, "/")
+		result.Changes[i].SizeDelta = int64(toSizes[path]) - int64(fromSizes[path])
+	}
+
+	return result, nil
+}
+
+// DiffLive returns changes from snapshot from to live sources, found by
+// dry run of backup with from as parent. Removed paths are not reported
+func (r BackupRepository) DiffLive(ctx context.Context, from string) (DiffResult, error) {
+	if err := r.preflight(); err != nil {
+		return DiffResult{}, fmt.Errorf("%s repository diff: %w", r.Backend.Type, err)
+	}
+	if err := r.Source.Validate(); err != nil {
+		return DiffResult{}, fmt.Errorf("%s repository diff: %w", r.Backend.Type, err)
+	}
+	r.unlockStale(ctx)
+
+	fromSizes, err := r.nodeSizes(ctx, from)
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("%s repository diff: %w", r.Backend.Type, err)
+	}
+
+	commandArg := append(r.commandArgs("backup"), "--dry-run", "-vv", "--parent", from)
+	commandArg = append(commandArg, r.Source.args()...)
+
+	var result DiffResult
+	err = runOperation(ctx, r.logger(), r.Run, OperationDiff, func(ctx context.Context) error {
+		result = DiffResult{From: from}
+		return r.execJSON(ctx, commandArg, func(line string) {
+			var message struct {
+				MessageType string `json:"message_type"`
+				Action      string `json:"action"`
+				Item        string `json:"item"`
+				DataSize    uint64 `json:"data_size"`
+			}
+			if err := json.Unmarshal([]byte(line), &message); err != nil {
+				r.logger().Debug("skip restic backup output", "line", line)
+				return
+			}
+			if message.MessageType != "verbose_status" {
+				return
+			}
+
+			// data_size is new data the backup would add, not size of the file,
+			// so size delta is taken from the live file
+			isDir := strings.HasSuffix(message.Item, "/")
+			var delta int64
+			if !isDir {
+				delta = int64(liveSize(message.Item)) - int64(fromSizes[message.Item])
+			}
+			switch message.Action {
+			case "new":
+				result.Changes = append(result.Changes, DiffChange{Path: message.Item, Modifier: DiffAdded, SizeDelta: delta})
+				if isDir {
+					result.Added.Dirs++
+				} else {
+					result.Added.Files++
+					result.Added.Bytes += message.DataSize
+				}
+			case "modified":
+				if isDir {
+					return
+				}
+				result.Changes = append(result.Changes, DiffChange{Path: message.Item, Modifier: DiffModified, SizeDelta: delta})
+				result.ChangedFiles++
+			}
+		})
+	})
+	if err != nil {
+		return DiffResult{}, fmt.Errorf("%s repository diff: %w", r.Backend.Type, err)
+	}
+
+	return result, nil
+}
+
+// nodeSizes returns sizes of snapshot nodes by path
+func (r BackupRepository) nodeSizes(ctx context.Context, snapshot string) (map[string]uint64, error) {
+	nodes, err := r.Ls(ctx, snapshot, LsOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make(map[string]uint64, len(nodes))
+	for _, node := range nodes {
+		sizes[node.Path] = node.Size
+	}
+
+	return sizes, nil
+}
+
+// liveSize returns size of regular file at path, 0 if it is not one or
+// cannot be read
+func liveSize(path string) uint64 {
+	info, err := os.Lstat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return 0
+	}
+
+	return uint64(info.Size())
+}
+
+// hasPathPrefix reports whether path is prefix or under directory prefix
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	path = strings.TrimSuffix(path, "/")
+
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
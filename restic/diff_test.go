@@ -0,0 +1,57 @@
+package restic_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/liuminhaw/wrestic-bkp/restic/resticfake"
+)
+
+func TestDiffLiveSizeDelta(t *testing.T) {
+	source := t.TempDir()
+	modified := filepath.Join(source, "modified.img")
+	added := filepath.Join(source, "added.txt")
+	if err := os.WriteFile(modified, make([]byte, 1000), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(added, make([]byte, 50), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Modified file shares most chunks with snapshot, so dry run adds only
+	// 10 bytes of new data, and added file is fully deduplicated
+	ls := fmt.Sprintf(`{"struct_type":"node","name":"modified.img","type":"file","path":%q,"size":900}`, modified)
+	backup := strings.Join([]string{
+		fmt.Sprintf(`{"message_type":"verbose_status","action":"modified","item":%q,"data_size":10}`, modified),
+		fmt.Sprintf(`{"message_type":"verbose_status","action":"new","item":%q,"data_size":0}`, added),
+		fmt.Sprintf(`{"message_type":"verbose_status","action":"modified","item":%q,"data_size":0}`, source+"/"),
+	}, "\n")
+	fake := &resticfake.Runner{Handler: func(call resticfake.Call) resticfake.Response {
+		if call.Args[0] == "ls" {
+			return resticfake.Response{Stdout: ls + "\n"}
+		}
+		return resticfake.Response{Stdout: backup + "\n"}
+	}}
+	repo := fakeRepository(fake, restic.RunSettings{})
+	repo.Source = restic.BackupSource{Sources: []string{source}}
+
+	result, err := repo.DiffLive(context.Background(), "aaaa1111")
+	if err != nil {
+		t.Fatalf("DiffLive() error = %v", err)
+	}
+
+	want := map[string]int64{modified: 100, added: 50}
+	if len(result.Changes) != len(want) {
+		t.Fatalf("DiffLive() changes = %+v, want %d changes", result.Changes, len(want))
+	}
+	for _, change := range result.Changes {
+		if change.SizeDelta != want[change.Path] {
+			t.Errorf("SizeDelta of %s = %d, want %d", change.Path, change.SizeDelta, want[change.Path])
+		}
+	}
+}
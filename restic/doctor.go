@@ -0,0 +1,270 @@
+package restic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const unlockHint string = "remove stale locks with 'wrestic-bkp run unlock BackupName' or set autoUnlockStale"
+
+// recommendedVersion is the oldest restic supporting every wrestic-bkp feature
+var recommendedVersion = Version{0, 16, 0}
+
+// Status is result of a doctor check
+type Status int
+
+const (
+	StatusPass Status = iota
+	StatusWarn
+	StatusFail
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusPass:
+		return "PASS"
+	case StatusWarn:
+		return "WARN"
+	default:
+		return "FAIL"
+	}
+}
+
+// Finding is result of a single doctor check, with hint on how to fix
+// warnings and failures
+type Finding struct {
+	Check   string
+	Status  Status
+	Message string
+	Hint    string
+}
+
+// Diagnoser is implemented by repositories able to check their own setup
+type Diagnoser interface {
+	Diagnose(ctx context.Context) []Finding
+}
+
+func pass(check, format string, a ...any) Finding {
+	return Finding{Check: check, Status: StatusPass, Message: fmt.Sprintf(format, a...)}
+}
+
+func warn(check, hint, format string, a ...any) Finding {
+	return Finding{Check: check, Status: StatusWarn, Message: fmt.Sprintf(format, a...), Hint: hint}
+}
+
+func fail(check, hint, format string, a ...any) Finding {
+	return Finding{Check: check, Status: StatusFail, Message: fmt.Sprintf(format, a...), Hint: hint}
+}
+
+// DiagnoseRestic checks restic binary of config is available and recent enough
+func DiagnoseRestic(ctx context.Context, config *Config) []Finding {
+	binary := config.Binary()
+	if err := ResticCheck(binary); err != nil {
+		return []Finding{
+			fail("restic binary", "install restic or set resticBinary in config", "%s not found", binary),
+		}
+	}
+	findings := []Finding{pass("restic binary", "%s", binary)}
+
+	version, err := ResticVersion(ctx, config.Runner())
+	switch {
+	case err != nil:
+		findings = append(findings, fail("restic version", "check that resticBinary is a restic program", "%v", err))
+	case !version.AtLeast(recommendedVersion):
+		findings = append(findings, warn("restic version",
+			fmt.Sprintf("upgrade restic to %s or newer", recommendedVersion),
+			"%s, some commands are not supported", version))
+	default:
+		findings = append(findings, pass("restic version", "%s", version))
+	}
+
+	return findings
+}
+
+// Diagnose checks backup config and, if supported by its repository,
+// sources, destination and repository state
+func (b Backup) Diagnose(ctx context.Context) []Finding {
+	if b.err != nil {
+		// Repository of invalid backup is not created
+		return []Finding{fail("config", "fix backup config in config file", "%v", b.err)}
+	}
+	findings := []Finding{pass("config", "valid")}
+
+	diagnoser, ok := b.repository.(Diagnoser)
+	if !ok {
+		return append(findings, warn("repository", "", "backup type %s does not support doctor checks", b.Type))
+	}
+
+	return append(findings, diagnoser.Diagnose(ctx)...)
+}
+
+// Diagnose reports settings of config file not belonging to a backup that are
+// invalid, kept by NewConfigForDiagnosis
+func (c *Config) Diagnose() []Finding {
+	if len(c.errs) == 0 {
+		return []Finding{pass("config", "valid")}
+	}
+
+	findings := []Finding{}
+	for _, err := range c.errs {
+		findings = append(findings, fail("config", "fix config file", "%v", err))
+	}
+
+	return findings
+}
+
+// Diagnose checks sources, backend setup, credentials and repository state
+func (r BackupRepository) Diagnose(ctx context.Context) []Finding {
+	findings := r.diagnoseSources()
+
+	if err := r.preflight(); err != nil {
+		findings = append(findings, fail("backend", ErrorHint(err), "%v", err))
+		return findings
+	}
+	findings = append(findings, pass("backend", "%s", r.Backend.Repository))
+
+	findings = append(findings, r.diagnoseCredentials()...)
+
+	repoFinding, opened := r.diagnoseRepository(ctx)
+	findings = append(findings, repoFinding)
+	if opened {
+		findings = append(findings, r.diagnoseLocks(ctx))
+	}
+
+	return findings
+}
+
+func (r BackupRepository) diagnoseSources() []Finding {
+	findings := []Finding{}
+	if len(r.Source.Sources) == 0 && len(r.Source.FilesFrom) == 0 {
+		return append(findings, warn("sources", "add sources to backup config", "no source path set"))
+	}
+	if err := r.Source.Validate(); err != nil {
+		findings = append(findings, fail("source options", "fix source options in backup config", "%v", err))
+	}
+
+	for _, source := range r.Source.Sources {
+		check := fmt.Sprintf("source %s", source)
+		f, err := os.Open(source)
+		if err != nil {
+			findings = append(findings, fail(check, "check that source path exists and is readable by current user", "%v", err))
+			continue
+		}
+		f.Close()
+		findings = append(findings, pass(check, "readable"))
+	}
+
+	return findings
+}
+
+// diagnoseCredentials warns about backend environments set to empty value
+func (r BackupRepository) diagnoseCredentials() []Finding {
+	findings := []Finding{}
+	for _, env := range r.Backend.Envs {
+		key, value, _ := strings.Cut(env, "=")
+		if value == "" {
+			findings = append(findings, warn("credentials", "set credential in backup config", "%s is empty", key))
+		}
+	}
+	if len(r.Backend.Envs) > 0 && len(findings) == 0 {
+		findings = append(findings, pass("credentials", "set"))
+	}
+
+	return findings
+}
+
+// diagnoseRepository opens repository config with 'restic cat config',
+// reporting whether destination is reachable, initialized and password is correct
+func (r BackupRepository) diagnoseRepository(ctx context.Context) (Finding, bool) {
+	const check = "repository"
+
+	commandArg := append(r.commandArgs("cat"), "config")
+	err := runOperation(ctx, r.logger(), r.Run, OperationDoctor, func(ctx context.Context) error {
+		_, err := r.execOutput(ctx, commandArg)
+		return err
+	})
+	if err == nil {
+		return pass(check, "initialized and password accepted"), true
+	}
+
+	if errors.Is(err, ErrTimeout) {
+		return fail(check, "check network connection to destination or doctor operation timeout", "%v", err), false
+	}
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return fail(check, "", "%v", err), false
+	}
+	message := firstLine(cmdErr.Stderr)
+	switch cmdErr.Kind {
+	case FailureRepoNotExist:
+		return warn(check, "initialize repository with 'wrestic-bkp run init'", "not initialized"), false
+	case FailureAuth:
+		return fail(check, "check repository password and backend credentials", "%s", message), false
+	case FailureNetwork:
+		return fail(check, "check network connection to destination", "destination unreachable: %s", message), false
+	case FailureLocked:
+		return warn(check, unlockHint, "%s", message), true
+	default:
+		return fail(check, "", "%s", message), false
+	}
+}
+
+// diagnoseLocks warns about stale locks, which may be left by interrupted runs
+func (r BackupRepository) diagnoseLocks(ctx context.Context) Finding {
+	const check = "locks"
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return warn(check, "", "%v", err)
+	}
+	var locks []Lock
+	err = runOperation(ctx, r.logger(), r.Run, OperationDoctor, func(ctx context.Context) error {
+		var err error
+		locks, err = r.Locks(ctx)
+		return err
+	})
+	if err != nil {
+		return warn(check, "", "%v", err)
+	}
+
+	own, other := staleLocks(locks, time.Now(), hostname)
+	stale := append(own, other...)
+	if len(stale) > 0 {
+		descriptions := []string{}
+		for _, lock := range stale {
+			descriptions = append(descriptions, lock.String())
+		}
+		return warn(check, unlockHint+"\n"+strings.Join(descriptions, "\n"), "%d stale lock(s) found", len(stale))
+	}
+	if len(locks) > 0 {
+		return pass(check, "%d lock(s) held by running restic", len(locks))
+	}
+
+	return pass(check, "no locks")
+}
+
+// firstLine returns first non empty line of s
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+
+	return s
+}
+
+// HasFailure reports whether any of findings failed
+func HasFailure(findings []Finding) bool {
+	for _, finding := range findings {
+		if finding.Status == StatusFail {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,89 @@
+package restic_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/liuminhaw/wrestic-bkp/restic/resticfake"
+)
+
+const diagnosisConfig string = `repository:
+  password: secret
+backups:
+- name: local
+  type: local
+  config:
+    sources: [/src]
+    destination: /repo
+- name: gs
+  type: gs
+  config:
+    bucket: backups
+- name: copy
+  type: local
+  copyTo: [missing]
+  config:
+    sources: [/src]
+    destination: /copy
+mounts:
+- name: home
+  backup: missing
+`
+
+func TestNewConfigForDiagnosis(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(diagnosisConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := restic.NewConfig(path); err == nil {
+		t.Fatal("NewConfig() succeeded, want error of invalid backup")
+	}
+
+	config, err := restic.NewConfigForDiagnosis(path)
+	if err != nil {
+		t.Fatalf("NewConfigForDiagnosis() error = %v", err)
+	}
+	if !restic.HasFailure(config.Diagnose()) {
+		t.Error("Config.Diagnose() has no failure of invalid mount")
+	}
+
+	wantConfigFailure := map[string]bool{"local": false, "gs": true, "copy": true}
+	for _, backup := range config.Backups {
+		findings := backup.Diagnose(context.Background())
+		if findings[0].Check != "config" {
+			t.Fatalf("backup %s first finding = %s, want config", backup.Name, findings[0].Check)
+		}
+		if failed := findings[0].Status == restic.StatusFail; failed != wantConfigFailure[backup.Name] {
+			t.Errorf("backup %s config finding = %s %s, want failure %t",
+				backup.Name, findings[0].Status, findings[0].Message, wantConfigFailure[backup.Name])
+		}
+	}
+}
+
+func TestDiagnoseRepositoryTimeout(t *testing.T) {
+	fake := &resticfake.Runner{Handler: func(call resticfake.Call) resticfake.Response {
+		time.Sleep(50 * time.Millisecond)
+		return resticfake.Response{}
+	}}
+	repo := fakeRepository(fake, restic.RunSettings{
+		Operations: map[string]restic.OperationSettings{restic.OperationDoctor: {Timeout: 10 * time.Millisecond}},
+	})
+
+	var repository restic.Finding
+	for _, finding := range repo.Diagnose(context.Background()) {
+		if finding.Check == "repository" {
+			repository = finding
+		}
+	}
+	if repository.Status != restic.StatusFail {
+		t.Errorf("repository finding = %s %s, want failure of timeout", repository.Status, repository.Message)
+	}
+	if calls := len(fake.Calls()); calls != 1 {
+		t.Errorf("restic called %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,78 @@
+package restic
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Archive formats of DumpOptions
+const (
+	ArchiveTar string = "tar"
+	ArchiveZip string = "zip"
+)
+
+// DumpOptions sets how Dump writes directories
+type DumpOptions struct {
+	// Archive is ArchiveTar or ArchiveZip, restic default tar if empty
+	Archive string
+}
+
+// Dumper is implemented by repositories writing files out of snapshots
+type Dumper interface {
+	Dump(ctx context.Context, snapshot, path string, opts DumpOptions, w io.Writer) error
+}
+
+// Validate checks that archive format is supported
+func (o DumpOptions) Validate() error {
+	switch o.Archive {
+	case "", ArchiveTar, ArchiveZip:
+		return nil
+	}
+
+	return fmt.Errorf("archive should be %s or %s: %s", ArchiveTar, ArchiveZip, o.Archive)
+}
+
+// Dump writes file at path in snapshot to w, or archive of path if it is a directory.
+// Failed dump is not retried once its output is written
+func (r BackupRepository) Dump(ctx context.Context, snapshot, path string, opts DumpOptions, w io.Writer) error {
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("%s repository dump: %w", r.Backend.Type, err)
+	}
+	if err := r.preflight(); err != nil {
+		return fmt.Errorf("%s repository dump: %w", r.Backend.Type, err)
+	}
+	r.unlockStale(ctx)
+
+	commandArg := append(r.commandArgs("dump"), snapshot, path)
+	if opts.Archive != "" {
+		commandArg = append(commandArg, "--archive", opts.Archive)
+	}
+
+	out := &countWriter{w: w}
+	err := runOperation(ctx, r.logger(), r.Run, OperationDump, func(ctx context.Context) error {
+		err := r.execWriter(ctx, "dump", commandArg, out)
+		if err != nil && out.n > 0 {
+			return fmt.Errorf("%w after %d bytes written: %w", errNoRetry, out.n, err)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s repository dump: %w", r.Backend.Type, err)
+	}
+
+	return nil
+}
+
+// countWriter counts bytes written to w
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
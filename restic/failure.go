@@ -0,0 +1,196 @@
+package restic
+
+import (
+	"errors"
+	"strings"
+)
+
+// FailureKind classifies why restic command failed
+type FailureKind int
+
+const (
+	FailureUnknown FailureKind = iota
+	FailureNetwork
+	FailureLocked
+	FailureAuth
+	FailureRepoNotExist
+)
+
+// restic exit codes, see 'restic --help'
+const (
+	exitCodeRepoNotExist  int = 10
+	exitCodeLockFailed    int = 11
+	exitCodeWrongPassword int = 12
+)
+
+// failurePatterns maps lower cased stderr fragments to failure kind,
+// checked in order so authentication errors win over network errors
+var failurePatterns = []struct {
+	kind     FailureKind
+	patterns []string
+}{
+	{
+		kind: FailureAuth,
+		patterns: []string{
+			"wrong password",
+			"no key found",
+			"access denied",
+			"accessdenied",
+			"invalidaccesskeyid",
+			"signaturedoesnotmatch",
+			"authentication failed",
+			"unable to authenticate",
+			"permission denied (publickey",
+			"401 unauthorized",
+			"403 forbidden",
+		},
+	},
+	{
+		kind: FailureRepoNotExist,
+		patterns: []string{
+			"is there a repository at the following location",
+			"repository does not exist",
+			"unable to open config file",
+		},
+	},
+	{
+		kind: FailureLocked,
+		patterns: []string{
+			"repository is already locked",
+			"unable to create lock",
+		},
+	},
+	{
+		kind: FailureNetwork,
+		patterns: []string{
+			"connection refused",
+			"connection reset",
+			"connection timed out",
+			"i/o timeout",
+			"no such host",
+			"network is unreachable",
+			"tls handshake timeout",
+			"broken pipe",
+			"unexpected eof",
+			"connection closed",
+			"ssh command exited",
+			"500 internal server error",
+			"502 bad gateway",
+			"503 service unavailable",
+			"504 gateway timeout",
+			"requesttimeout",
+			"slowdown",
+		},
+	},
+}
+
+func (k FailureKind) String() string {
+	switch k {
+	case FailureNetwork:
+		return "network"
+	case FailureLocked:
+		return "locked"
+	case FailureAuth:
+		return "auth"
+	case FailureRepoNotExist:
+		return "repository not exist"
+	default:
+		return "unknown"
+	}
+}
+
+// Transient reports whether failure of kind may succeed on retry
+func (k FailureKind) Transient() bool {
+	return k == FailureNetwork || k == FailureLocked
+}
+
+// CommandError is returned when restic exits unsuccessfully
+type CommandError struct {
+	ExitCode int
+	Stderr   string
+	Kind     FailureKind
+	Err      error
+}
+
+func (e *CommandError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// newCommandError creates CommandError from err returned by restic command
+// with failure kind classified from exit code and stderr
+func newCommandError(stderr []byte, err error) *CommandError {
+	cmdErr := &CommandError{
+		ExitCode: -1,
+		Stderr:   string(stderr),
+		Err:      err,
+	}
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		cmdErr.ExitCode = exitErr.ExitCode()
+	}
+	cmdErr.Kind = classifyFailure(cmdErr.ExitCode, cmdErr.Stderr)
+
+	return cmdErr
+}
+
+// classifyFailure returns failure kind from restic exit code and stderr output
+func classifyFailure(exitCode int, stderr string) FailureKind {
+	switch exitCode {
+	case exitCodeWrongPassword:
+		return FailureAuth
+	case exitCodeLockFailed:
+		return FailureLocked
+	case exitCodeRepoNotExist:
+		return FailureRepoNotExist
+	}
+
+	stderr = strings.ToLower(stderr)
+	for _, failure := range failurePatterns {
+		for _, pattern := range failure.patterns {
+			if strings.Contains(stderr, pattern) {
+				return failure.kind
+			}
+		}
+	}
+
+	return FailureUnknown
+}
+
+// failureKind returns failure kind of restic CommandError in err chain,
+// FailureUnknown if there is none
+func failureKind(err error) FailureKind {
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return FailureUnknown
+	}
+
+	return cmdErr.Kind
+}
+
+// HintError is an error with hint on how to fix it
+type HintError struct {
+	Err  error
+	Hint string
+}
+
+func (e *HintError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *HintError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorHint returns hint of HintError in err chain, empty string if there is none
+func ErrorHint(err error) string {
+	var hintErr *HintError
+	if !errors.As(err, &hintErr) {
+		return ""
+	}
+
+	return hintErr.Hint
+}
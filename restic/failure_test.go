@@ -0,0 +1,76 @@
+package restic
+
+import (
+	"errors"
+	"testing"
+)
+
+type exitCodeError int
+
+func (e exitCodeError) Error() string { return "exit status" }
+func (e exitCodeError) ExitCode() int { return int(e) }
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		exitCode  int
+		stderr    string
+		want      FailureKind
+		transient bool
+	}{
+		{"wrong password exit code", 12, "", FailureAuth, false},
+		{"lock failed exit code", 11, "", FailureLocked, true},
+		{"repository not exist exit code", 10, "", FailureRepoNotExist, false},
+		{"exit code wins over stderr", 12, "connection reset by peer", FailureAuth, false},
+		{"wrong password stderr", 1, "Fatal: wrong password or no key found", FailureAuth, false},
+		{"auth wins over network", 1, "403 Forbidden: connection reset", FailureAuth, false},
+		{"missing repository stderr", 1, "Fatal: unable to open config file: stat /repo/config", FailureRepoNotExist, false},
+		{"locked stderr", 1, "unable to create lock in backend: repository is already locked", FailureLocked, true},
+		{"connection reset", 1, "Load(<data/1234>) failed: read tcp: connection reset by peer", FailureNetwork, true},
+		{"upper case stderr", 1, "DIAL TCP: I/O TIMEOUT", FailureNetwork, true},
+		{"service unavailable", 1, "client.PutObject: 503 Service Unavailable", FailureNetwork, true},
+		{"unknown", 1, "Fatal: invalid argument", FailureUnknown, false},
+		{"no stderr", 1, "", FailureUnknown, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyFailure(tt.exitCode, tt.stderr)
+			if got != tt.want {
+				t.Errorf("classifyFailure(%d, %q) = %s, want %s", tt.exitCode, tt.stderr, got, tt.want)
+			}
+			if got.Transient() != tt.transient {
+				t.Errorf("%s.Transient() = %t, want %t", got, got.Transient(), tt.transient)
+			}
+		})
+	}
+}
+
+func TestNewCommandError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		stderr   string
+		wantCode int
+		wantKind FailureKind
+	}{
+		{"exit code of error", exitCodeError(11), "", 11, FailureLocked},
+		{"wrapped exit code", errors.Join(errors.New("run"), exitCodeError(12)), "", 12, FailureAuth},
+		{"no exit code", errors.New("exec: not found"), "no such host", -1, FailureNetwork},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmdErr := newCommandError([]byte(tt.stderr), tt.err)
+			if cmdErr.ExitCode != tt.wantCode {
+				t.Errorf("ExitCode = %d, want %d", cmdErr.ExitCode, tt.wantCode)
+			}
+			if cmdErr.Kind != tt.wantKind {
+				t.Errorf("Kind = %s, want %s", cmdErr.Kind, tt.wantKind)
+			}
+			if !errors.Is(cmdErr, tt.err) {
+				t.Errorf("CommandError does not wrap %v", tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package restic
+
+import (
+	"errors"
+	"os"
+)
+
+var errFlockUnsupported = errors.New("run lock is not supported on this platform")
+
+func tryLockFile(file *os.File) (bool, error) {
+	return false, errFlockUnsupported
+}
+
+func unlockFile(file *os.File) error {
+	return errFlockUnsupported
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package restic
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// tryLockFile takes exclusive flock on file without blocking,
+// reporting false if lock is held by another process
+func tryLockFile(file *os.File) (bool, error) {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,77 @@
+package restic
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	googleProjectIdEnv       string = "GOOGLE_PROJECT_ID"
+	googleCredentialsFileEnv string = "GOOGLE_APPLICATION_CREDENTIALS"
+)
+
+func init() {
+	RegisterBackend("gs", NewBackendFactory(func() BackendConfig { return &GsBackupConfig{} }))
+}
+
+type GsBackupConfig struct {
+	ProjectId       string `yaml:"projectId"`
+	CredentialsFile string `yaml:"credentialsFile"`
+	Bucket          string `yaml:"bucket"`
+	BackupSource    `yaml:",inline"`
+	Destination     string `yaml:"destination"`
+}
+
+func (c GsBackupConfig) Validate() error {
+	if c.ProjectId == "" {
+		return errors.New("gs config: projectId is required")
+	}
+	if c.Bucket == "" {
+		return errors.New("gs config: bucket is required")
+	}
+
+	return nil
+}
+
+func (c GsBackupConfig) String() string {
+	var builder strings.Builder
+	builder.WriteString(srcDestString(c.Sources, c.Destination))
+	builder.WriteString(fmt.Sprintf("Bucket: %s\n", c.Bucket))
+	builder.WriteString(fmt.Sprintf("Project ID: %s\n", c.ProjectId))
+	builder.WriteString(fmt.Sprintf("Credentials File: %s\n", c.CredentialsFile))
+
+	return builder.String()
+}
+
+// Backend returns gs repository in format gs:bucket:/path
+func (c GsBackupConfig) Backend() Backend {
+	envs := []string{envPair(googleProjectIdEnv, c.ProjectId)}
+	// Without credentialsFile, credentials of caller environment are used
+	if c.CredentialsFile != "" {
+		envs = append(envs, envPair(googleCredentialsFileEnv, c.CredentialsFile))
+	}
+
+	return Backend{
+		Type:       "gs",
+		Repository: fmt.Sprintf("gs:%s:/%s", c.Bucket, strings.TrimPrefix(c.Destination, "/")),
+		Envs:       envs,
+		Preflight:  c.checkCredentials,
+	}
+}
+
+// checkCredentials checks that credentialsFile, if set, exists
+func (c GsBackupConfig) checkCredentials() error {
+	if c.CredentialsFile == "" {
+		return nil
+	}
+	if _, err := os.Stat(c.CredentialsFile); err != nil {
+		return &HintError{
+			Err:  fmt.Errorf("credentials file: %w", err),
+			Hint: "check credentialsFile setting of gs backup config",
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package restic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Key is a key of repository listed by restic key list --json
+type Key struct {
+	ID       string `json:"id"`
+	Current  bool   `json:"current"`
+	UserName string `json:"userName"`
+	HostName string `json:"hostName"`
+	Created  string `json:"created"`
+}
+
+// KeyOptions sets user and host names recorded with added key
+type KeyOptions struct {
+	User string
+	Host string
+}
+
+// KeyManager is implemented by repositories managing their keys
+type KeyManager interface {
+	Keys(ctx context.Context) ([]Key, error)
+	AddKey(ctx context.Context, newPassword string, opts KeyOptions) error
+	RemoveKey(ctx context.Context, id string) error
+	ChangePassword(ctx context.Context, newPassword string) error
+}
+
+// Keys returns keys of repository
+func (r BackupRepository) Keys(ctx context.Context) ([]Key, error) {
+	if err := r.preflight(); err != nil {
+		return nil, fmt.Errorf("%s repository keys: %w", r.Backend.Type, err)
+	}
+
+	var output []byte
+	err := runOperation(ctx, r.logger(), r.Run, OperationKey, func(ctx context.Context) error {
+		var err error
+		output, err = r.execOutput(ctx, append(r.commandArgs("key"), "list", "--json"))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s repository keys: %w", r.Backend.Type, err)
+	}
+
+	keys := []Key{}
+	if err := json.Unmarshal(output, &keys); err != nil {
+		return nil, fmt.Errorf("%s repository keys: decode: %w", r.Backend.Type, err)
+	}
+
+	return keys, nil
+}
+
+// AddKey adds key with newPassword to repository
+func (r BackupRepository) AddKey(ctx context.Context, newPassword string, opts KeyOptions) error {
+	commandArg := append(r.commandArgs("key"), "add")
+	if opts.User != "" {
+		commandArg = append(commandArg, "--user", opts.User)
+	}
+	if opts.Host != "" {
+		commandArg = append(commandArg, "--host", opts.Host)
+	}
+
+	if err := r.runKeyCommand(ctx, commandArg, newPassword); err != nil {
+		return fmt.Errorf("%s repository add key: %w", r.Backend.Type, err)
+	}
+
+	return nil
+}
+
+// RemoveKey removes key with id from repository
+func (r BackupRepository) RemoveKey(ctx context.Context, id string) error {
+	commandArg := append(r.commandArgs("key"), "remove", id)
+	if err := r.runKeyCommand(ctx, commandArg, ""); err != nil {
+		return fmt.Errorf("%s repository remove key: %w", r.Backend.Type, err)
+	}
+
+	return nil
+}
+
+// ChangePassword replaces key of repository password with key of newPassword
+func (r BackupRepository) ChangePassword(ctx context.Context, newPassword string) error {
+	commandArg := append(r.commandArgs("key"), "passwd")
+	if err := r.runKeyCommand(ctx, commandArg, newPassword); err != nil {
+		return fmt.Errorf("%s repository change password: %w", r.Backend.Type, err)
+	}
+
+	return nil
+}
+
+// runKeyCommand runs restic key command with newPassword, if set, given in
+// a temporary file readable by current user only
+func (r BackupRepository) runKeyCommand(ctx context.Context, commandArg []string, newPassword string) error {
+	if err := r.preflight(); err != nil {
+		return err
+	}
+
+	if newPassword != "" {
+		file, err := os.CreateTemp("", "wrestic-bkp-key-*")
+		if err != nil {
+			return fmt.Errorf("new password file: %w", err)
+		}
+		defer os.Remove(file.Name())
+		_, err = file.WriteString(newPassword)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("new password file: %w", err)
+		}
+		commandArg = append(commandArg, "--new-password-file", file.Name())
+	}
+
+	// Key changes are not retried, a failed attempt may have been applied
+	r.logger().Info("restic key", "command", commandArg[len(r.commandArgs("key")):])
+	_, err := r.execOutput(ctx, commandArg)
+
+	return err
+}
@@ -0,0 +1,44 @@
+package restic
+
+import (
+	"strings"
+
+	"github.com/liuminhaw/wrestic-bkp/restic/tests"
+)
+
+func init() {
+	RegisterBackend("local", NewBackendFactory(func() BackendConfig { return &LocalBackupConfig{} }))
+}
+
+type LocalBackupConfig struct {
+	BackupSource `yaml:",inline"`
+	Destination  string `yaml:"destination"`
+}
+
+func (c LocalBackupConfig) Validate() error {
+	return nil
+}
+
+func (c LocalBackupConfig) String() string {
+	var builder strings.Builder
+	builder.WriteString(srcDestString(c.Sources, c.Destination))
+
+	return builder.String()
+}
+
+func (c LocalBackupConfig) Backend() Backend {
+	return Backend{
+		Type:       "local",
+		Repository: c.Destination,
+	}
+}
+
+func (c LocalBackupConfig) test(repository ConfigRepository) tests.ResticTest {
+	return tests.LocalRepositoryTest{
+		Password:        repository.Password,
+		PasswordFile:    repository.PasswordFile,
+		PasswordCommand: repository.PasswordCommand,
+		Destination:     c.Destination,
+		Sources:         c.Sources,
+	}
+}
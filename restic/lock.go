@@ -0,0 +1,189 @@
+package restic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// staleLockAge is the age after which locks of other hosts are considered stale,
+// same as restic which refreshes its locks every 5 minutes
+const staleLockAge time.Duration = 30 * time.Minute
+
+// Unlocker is implemented by repositories supporting lock management
+type Unlocker interface {
+	// Locks returns locks currently in repository
+	Locks(ctx context.Context) ([]Lock, error)
+	// Unlock removes stale locks, or all locks if removeAll is true
+	Unlock(ctx context.Context, removeAll bool) ([]byte, error)
+}
+
+// Lock is a restic repository lock read by 'restic cat lock'
+type Lock struct {
+	ID        string    `json:"-"`
+	Time      time.Time `json:"time"`
+	Exclusive bool      `json:"exclusive"`
+	Hostname  string    `json:"hostname"`
+	Username  string    `json:"username"`
+	PID       int       `json:"pid"`
+}
+
+// Own reports whether lock is created on host hostname
+func (l Lock) Own(hostname string) bool {
+	return l.Hostname == hostname
+}
+
+// Stale reports whether lock is left by a process no longer running.
+// Locks of host hostname are stale if its process is gone, locks of other
+// hosts are stale if older than staleLockAge
+func (l Lock) Stale(now time.Time, hostname string) bool {
+	if l.Own(hostname) {
+		return !processAlive(l.PID)
+	}
+
+	return now.Sub(l.Time) > staleLockAge
+}
+
+func (l Lock) String() string {
+	kind := "shared"
+	if l.Exclusive {
+		kind = "exclusive"
+	}
+
+	return fmt.Sprintf("%s %s lock by %s@%s pid %d at %s",
+		shortID(l.ID), kind, l.Username, l.Hostname, l.PID, l.Time.Format(time.RFC3339))
+}
+
+func (r BackupRepository) Locks(ctx context.Context) ([]Lock, error) {
+	if err := r.preflight(); err != nil {
+		return nil, fmt.Errorf("%s repository locks: %w", r.Backend.Type, err)
+	}
+
+	commandArg := append(r.commandArgs("list"), "locks", "--no-lock")
+	output, err := r.execOutput(ctx, commandArg)
+	if err != nil {
+		return nil, fmt.Errorf("%s repository locks: %w", r.Backend.Type, err)
+	}
+
+	locks := []Lock{}
+	for _, id := range strings.Fields(string(output)) {
+		commandArg := append(r.commandArgs("cat"), "lock", id, "--no-lock")
+		data, err := r.execOutput(ctx, commandArg)
+		if err != nil {
+			// Lock may be removed by its owner in between
+			if failureKind(err) == FailureRepoNotExist {
+				continue
+			}
+			return nil, fmt.Errorf("%s repository locks: cat lock %s: %w", r.Backend.Type, shortID(id), err)
+		}
+
+		lock := Lock{ID: id}
+		if err := json.Unmarshal(data, &lock); err != nil {
+			return nil, fmt.Errorf("%s repository locks: decode lock %s: %w", r.Backend.Type, shortID(id), err)
+		}
+		locks = append(locks, lock)
+	}
+
+	return locks, nil
+}
+
+func (r BackupRepository) Unlock(ctx context.Context, removeAll bool) ([]byte, error) {
+	if err := r.preflight(); err != nil {
+		return nil, fmt.Errorf("%s repository unlock: %w", r.Backend.Type, err)
+	}
+
+	commandArg := r.commandArgs("unlock")
+	if removeAll {
+		commandArg = append(commandArg, "--remove-all")
+	}
+	output, err := r.execOutput(ctx, commandArg)
+	if err != nil {
+		return output, fmt.Errorf("%s repository unlock: %w", r.Backend.Type, err)
+	}
+
+	return output, nil
+}
+
+// unlockStale removes stale locks left by this host when AutoUnlockStale is set.
+// Since 'restic unlock' removes every stale lock, it is skipped if stale
+// locks of other hosts exist
+func (r BackupRepository) unlockStale(ctx context.Context) {
+	if !r.Run.AutoUnlockStale {
+		return
+	}
+
+	logger := r.logger().With("repository", r.Backend.Repository)
+	hostname, err := os.Hostname()
+	if err != nil {
+		logger.Warn("unlock stale", "error", err)
+		return
+	}
+	locks, err := r.Locks(ctx)
+	if err != nil {
+		logger.Warn("unlock stale", "error", err)
+		return
+	}
+
+	ownStale, otherStale := staleLocks(locks, time.Now(), hostname)
+	if len(otherStale) > 0 {
+		for _, lock := range otherStale {
+			logger.Warn("unlock stale skipped, stale lock of other host", "lock", lock.String())
+		}
+		return
+	}
+	if len(ownStale) == 0 {
+		return
+	}
+
+	for _, lock := range ownStale {
+		logger.Info("unlock stale lock", "lock", lock.String())
+	}
+	if _, err := r.Unlock(ctx, false); err != nil {
+		logger.Warn("unlock stale", "error", err)
+	}
+}
+
+// staleLocks splits stale locks into those created on hostname and on other hosts
+func staleLocks(locks []Lock, now time.Time, hostname string) (own, other []Lock) {
+	for _, lock := range locks {
+		if !lock.Stale(now, hostname) {
+			continue
+		}
+		if lock.Own(hostname) {
+			own = append(own, lock)
+		} else {
+			other = append(other, lock)
+		}
+	}
+
+	return own, other
+}
+
+// processAlive reports whether process pid exists on this host
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	err = process.Signal(syscall.Signal(0))
+	// Process owned by other user
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// shortID returns first 8 characters of restic ID
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+
+	return id
+}
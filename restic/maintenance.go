@@ -0,0 +1,161 @@
+package restic
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// Targets of Repair
+const (
+	RepairIndex     string = "index"
+	RepairSnapshots string = "snapshots"
+	RepairPacks     string = "packs"
+)
+
+var (
+	sizeLimit   = regexp.MustCompile(`^\d+[kKmMgGtT]?$`)
+	unusedLimit = regexp.MustCompile(`^(\d+(\.\d+)?%|\d+[kKmMgGtT]?|unlimited)$`)
+)
+
+// repairFeatures maps Repair target to its restic feature
+var repairFeatures = map[string]string{
+	RepairIndex:     FeatureRepairIndex,
+	RepairSnapshots: FeatureRepairSnapshot,
+	RepairPacks:     FeatureRepairPacks,
+}
+
+// PruneOptions tunes data repacked by Prune
+type PruneOptions struct {
+	// MaxUnused is unused data tolerated in repository, as "5%", size
+	// such as "2G" or "unlimited", restic default if empty
+	MaxUnused string
+	// MaxRepackSize limits data repacked by single prune, as size such as "10G"
+	MaxRepackSize string
+	// DryRun only reports what would be removed
+	DryRun bool
+}
+
+// RepairOptions are options of Repair
+type RepairOptions struct {
+	// Forget removes snapshots whose data is lost instead of saving what is
+	// left of them, with RepairSnapshots
+	Forget bool
+	// Packs are IDs of damaged packs salvaged by RepairPacks
+	Packs []string
+}
+
+// Maintainer is implemented by repositories running maintenance commands.
+// Output of commands is streamed to stdout
+type Maintainer interface {
+	Prune(ctx context.Context, opts PruneOptions) error
+	Repair(ctx context.Context, target string, opts RepairOptions) error
+	Migrate(ctx context.Context, migration string) error
+}
+
+// Validate checks that limits are percentage or size
+func (o PruneOptions) Validate() error {
+	if o.MaxUnused != "" && !unusedLimit.MatchString(o.MaxUnused) {
+		return fmt.Errorf("prune: max unused should be percentage, size or unlimited: %s", o.MaxUnused)
+	}
+	if o.MaxRepackSize != "" && !sizeLimit.MatchString(o.MaxRepackSize) {
+		return fmt.Errorf("prune: max repack size should be size, e.g. 10G: %s", o.MaxRepackSize)
+	}
+
+	return nil
+}
+
+// Validate checks that options are given for target of Repair
+func (o RepairOptions) Validate(target string) error {
+	if _, ok := repairFeatures[target]; !ok {
+		return fmt.Errorf("repair: target should be %s, %s or %s: %s", RepairIndex, RepairSnapshots, RepairPacks, target)
+	}
+	if o.Forget && target != RepairSnapshots {
+		return fmt.Errorf("repair: forget is only used with %s", RepairSnapshots)
+	}
+	if (len(o.Packs) > 0) != (target == RepairPacks) {
+		return fmt.Errorf("repair: pack IDs are required by and only used with %s", RepairPacks)
+	}
+
+	return nil
+}
+
+// Prune removes data not referenced by snapshots from repository
+func (r BackupRepository) Prune(ctx context.Context, opts PruneOptions) error {
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("%s repository prune: %w", r.Backend.Type, err)
+	}
+
+	commandArg := r.commandArgs("prune")
+	if opts.MaxUnused != "" {
+		commandArg = append(commandArg, "--max-unused", opts.MaxUnused)
+	}
+	if opts.MaxRepackSize != "" {
+		commandArg = append(commandArg, "--max-repack-size", opts.MaxRepackSize)
+	}
+	if opts.DryRun {
+		commandArg = append(commandArg, "--dry-run")
+	}
+
+	if err := r.runMaintenance(ctx, OperationPrune, commandArg); err != nil {
+		return fmt.Errorf("%s repository prune: %w", r.Backend.Type, err)
+	}
+
+	return nil
+}
+
+// Repair rebuilds index, removes damaged data from snapshots or salvages
+// damaged packs of repository, as selected by target
+func (r BackupRepository) Repair(ctx context.Context, target string, opts RepairOptions) error {
+	if err := opts.Validate(target); err != nil {
+		return fmt.Errorf("%s repository repair: %w", r.Backend.Type, err)
+	}
+	if err := RequireFeature(ctx, r.runner(), repairFeatures[target]); err != nil {
+		return fmt.Errorf("%s repository repair: %w", r.Backend.Type, err)
+	}
+
+	commandArg := append(r.commandArgs("repair"), target)
+	if opts.Forget {
+		commandArg = append(commandArg, "--forget")
+	}
+	commandArg = append(commandArg, opts.Packs...)
+
+	if err := r.runMaintenance(ctx, OperationRepair, commandArg); err != nil {
+		return fmt.Errorf("%s repository repair %s: %w", r.Backend.Type, target, err)
+	}
+
+	return nil
+}
+
+// Migrate applies migration to repository, e.g. "upgrade_repo_v2".
+// Available migrations are listed if migration is empty
+func (r BackupRepository) Migrate(ctx context.Context, migration string) error {
+	commandArg := r.commandArgs("migrate")
+	if migration != "" {
+		commandArg = append(commandArg, migration)
+	}
+
+	if err := r.runMaintenance(ctx, OperationMigrate, commandArg); err != nil {
+		return fmt.Errorf("%s repository migrate: %w", r.Backend.Type, err)
+	}
+
+	return nil
+}
+
+// runMaintenance runs restic maintenance command of operation, streaming its output.
+// Only failures to lock repository are retried, other failures may come after
+// repository was partially modified
+func (r BackupRepository) runMaintenance(ctx context.Context, operation string, commandArg []string) error {
+	if err := r.preflight(); err != nil {
+		return err
+	}
+	r.unlockStale(ctx)
+
+	return runOperation(ctx, r.logger(), r.Run, operation, func(ctx context.Context) error {
+		err := r.execStream(ctx, commandArg)
+		if err != nil && failureKind(err).Transient() && failureKind(err) != FailureLocked {
+			return fmt.Errorf("%w, repository may be partially modified: %w", errNoRetry, err)
+		}
+		return err
+	})
+}
@@ -0,0 +1,39 @@
+package restic_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/liuminhaw/wrestic-bkp/restic/resticfake"
+)
+
+func TestPruneRetry(t *testing.T) {
+	lockedFailure := resticfake.Response{ExitCode: 11, Stderr: "repository is already locked"}
+	networkFailure := resticfake.Response{ExitCode: 1, Stderr: "Fatal: read tcp: connection reset by peer"}
+
+	tests := []struct {
+		name      string
+		responses []resticfake.Response
+		wantCalls int
+		wantErr   bool
+	}{
+		{"locked retried", []resticfake.Response{lockedFailure, {}}, 2, false},
+		{"network not retried", []resticfake.Response{networkFailure, {}}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := resticfake.New(tt.responses...)
+			repo := fakeRepository(fake, restic.RunSettings{Retry: fastRetry})
+
+			err := repo.Prune(context.Background(), restic.PruneOptions{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Prune() error = %v, want error %t", err, tt.wantErr)
+			}
+			if calls := len(fake.Calls()); calls != tt.wantCalls {
+				t.Errorf("restic called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
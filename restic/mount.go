@@ -0,0 +1,120 @@
+package restic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+const fuseHint string = `restic mount requires FUSE, install it with e.g.
+    apt install fuse3    (Debian, Ubuntu)
+    dnf install fuse3    (Fedora, RHEL)`
+
+var (
+	ErrConfigMountNameNotFound = errors.New("mount name in config not found")
+	ErrFusermountNotFound      = errors.New("fusermount not found")
+	ErrMountpointNotEmpty      = errors.New("mountpoint is not an empty directory")
+)
+
+// Mount is a named mount of backup repository in config file
+type Mount struct {
+	Name string `yaml:"name"`
+	// Backup is name of backup whose repository is mounted
+	Backup string `yaml:"backup"`
+	// Mountpoint is default directory repository is mounted on
+	Mountpoint string `yaml:"mountpoint,omitempty"`
+	// Paths, Tags and Hosts limit snapshots shown in mount
+	Paths []string `yaml:"paths,omitempty"`
+	Tags  []string `yaml:"tags,omitempty"`
+	Hosts []string `yaml:"hosts,omitempty"`
+	// DefaultPassword uses repository password from config, restic asks
+	// for password if set to false. Default to true
+	DefaultPassword *bool `yaml:"defaultPassword,omitempty"`
+}
+
+// MountOptions sets snapshots shown by Mount and how password is given
+type MountOptions struct {
+	Paths []string
+	Tags  []string
+	Hosts []string
+	// PromptPassword lets restic ask for repository password on terminal
+	PromptPassword bool
+}
+
+// Mounter is implemented by repositories mounted as file system
+type Mounter interface {
+	Mount(ctx context.Context, mountpoint string, opts MountOptions) error
+}
+
+// Options returns mount options of m
+func (m Mount) Options() MountOptions {
+	return MountOptions{
+		Paths:          m.Paths,
+		Tags:           m.Tags,
+		Hosts:          m.Hosts,
+		PromptPassword: m.DefaultPassword != nil && !*m.DefaultPassword,
+	}
+}
+
+// Mount mounts repository on mountpoint in foreground until ctx is done, then restic
+// unmounts it. Operation timeout and retry do not apply to mount
+func (r BackupRepository) Mount(ctx context.Context, mountpoint string, opts MountOptions) error {
+	if err := CheckMountpoint(mountpoint); err != nil {
+		return fmt.Errorf("%s repository mount: %w", r.Backend.Type, err)
+	}
+	if err := r.preflight(); err != nil {
+		return fmt.Errorf("%s repository mount: %w", r.Backend.Type, err)
+	}
+
+	commandArg := append(r.commandArgs("mount"), mountpoint)
+	for _, path := range opts.Paths {
+		commandArg = append(commandArg, "--path", path)
+	}
+	for _, tag := range opts.Tags {
+		commandArg = append(commandArg, "--tag", tag)
+	}
+	for _, host := range opts.Hosts {
+		commandArg = append(commandArg, "--host", host)
+	}
+	if opts.PromptPassword {
+		r.Password, r.PasswordFile, r.PasswordCommand = "", "", ""
+	}
+
+	r.logger().Debug("run restic", "args", commandArg)
+	err := r.runner().Run(ctx, Command{
+		Args:   commandArg,
+		Env:    r.envs(),
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("%s repository mount: %w", r.Backend.Type, commandError(ctx, "mount", nil, err))
+	}
+
+	return nil
+}
+
+// CheckMountpoint checks that FUSE is available and mountpoint is an empty directory
+func CheckMountpoint(mountpoint string) error {
+	if runtime.GOOS == "linux" {
+		if _, err := exec.LookPath("fusermount3"); err != nil {
+			if _, err := exec.LookPath("fusermount"); err != nil {
+				return &HintError{Err: ErrFusermountNotFound, Hint: fuseHint}
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(mountpoint)
+	if err != nil {
+		return fmt.Errorf("check mountpoint: %w", err)
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("check mountpoint %s: %w", mountpoint, ErrMountpointNotEmpty)
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package restic
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// BackupSummary is the result of a backup reported by restic
+type BackupSummary struct {
+	SnapshotID      string
+	FilesNew        uint64
+	FilesChanged    uint64
+	FilesUnmodified uint64
+	DirsNew         uint64
+	DirsChanged     uint64
+	DirsUnmodified  uint64
+	// DataAdded is size in bytes of data added to repository
+	DataAdded uint64
+	// TotalFiles and TotalBytes are files and bytes processed by backup
+	TotalFiles uint64
+	TotalBytes uint64
+	Duration   time.Duration
+}
+
+// jsonMessage is a message of restic --json output stream. Fields are
+// union of status, summary and error messages
+type jsonMessage struct {
+	MessageType string `json:"message_type"`
+
+	// status message
+	SecondsElapsed   float64 `json:"seconds_elapsed"`
+	SecondsRemaining float64 `json:"seconds_remaining"`
+	PercentDone      float64 `json:"percent_done"`
+	TotalFiles       uint64  `json:"total_files"`
+	FilesDone        uint64  `json:"files_done"`
+	TotalBytes       uint64  `json:"total_bytes"`
+	BytesDone        uint64  `json:"bytes_done"`
+	ErrorCount       uint64  `json:"error_count"`
+
+	// backup summary message
+	SnapshotID          string  `json:"snapshot_id"`
+	FilesNew            uint64  `json:"files_new"`
+	FilesChanged        uint64  `json:"files_changed"`
+	FilesUnmodified     uint64  `json:"files_unmodified"`
+	DirsNew             uint64  `json:"dirs_new"`
+	DirsChanged         uint64  `json:"dirs_changed"`
+	DirsUnmodified      uint64  `json:"dirs_unmodified"`
+	DataAdded           uint64  `json:"data_added"`
+	TotalFilesProcessed uint64  `json:"total_files_processed"`
+	TotalBytesProcessed uint64  `json:"total_bytes_processed"`
+	TotalDuration       float64 `json:"total_duration"`
+
+	// error message
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	During string `json:"during"`
+	Item   string `json:"item"`
+}
+
+func (m jsonMessage) backupSummary() BackupSummary {
+	return BackupSummary{
+		SnapshotID:      m.SnapshotID,
+		FilesNew:        m.FilesNew,
+		FilesChanged:    m.FilesChanged,
+		FilesUnmodified: m.FilesUnmodified,
+		DirsNew:         m.DirsNew,
+		DirsChanged:     m.DirsChanged,
+		DirsUnmodified:  m.DirsUnmodified,
+		DataAdded:       m.DataAdded,
+		TotalFiles:      m.TotalFilesProcessed,
+		TotalBytes:      m.TotalBytesProcessed,
+		Duration:        time.Duration(m.TotalDuration * float64(time.Second)),
+	}
+}
+
+// progressLine renders restic status messages as a single line redrawn in
+// place on terminal. Nothing is rendered if stdout is not a terminal
+type progressLine struct {
+	active bool
+}
+
+func (p *progressLine) update(m jsonMessage) {
+	if !stdoutTerminal {
+		return
+	}
+
+	line := fmt.Sprintf("[%s] %5.1f%%  %d/%d files  %s/%s",
+		formatSeconds(m.SecondsElapsed), m.PercentDone*100,
+		m.FilesDone, m.TotalFiles, FormatBytes(m.BytesDone), FormatBytes(m.TotalBytes))
+	if m.SecondsRemaining > 0 {
+		line += fmt.Sprintf("  ETA %s", formatSeconds(m.SecondsRemaining))
+	}
+	if m.ErrorCount > 0 {
+		line += fmt.Sprintf("  %d errors", m.ErrorCount)
+	}
+	fmt.Printf("\r\033[K%s", line)
+	p.active = true
+}
+
+// println prints line below progress line
+func (p *progressLine) println(line string) {
+	p.done()
+	fmt.Println(line)
+}
+
+// done ends progress line, so following output starts on a new line
+func (p *progressLine) done() {
+	if p.active {
+		fmt.Println()
+		p.active = false
+	}
+}
+
+// backupProgress handles restic backup --json output lines, rendering
+// status messages and keeping summary message
+type backupProgress struct {
+	logger   *slog.Logger
+	progress progressLine
+	summary  *BackupSummary
+}
+
+func (b *backupProgress) handle(line string) {
+	var message jsonMessage
+	if err := json.Unmarshal([]byte(line), &message); err != nil {
+		// Not a JSON message, e.g. restic notice
+		b.progress.println(line)
+		return
+	}
+
+	switch message.MessageType {
+	case "status":
+		b.progress.update(message)
+	case "summary":
+		b.progress.done()
+		summary := message.backupSummary()
+		b.summary = &summary
+	case "error":
+		b.progress.done()
+		b.logger.Warn("restic backup error",
+			"item", message.Item,
+			"during", message.During,
+			"error", message.Error.Message)
+	}
+}
+
+// FormatBytes formats size in bytes with binary unit, e.g. "1.5 MiB"
+func FormatBytes(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	value := float64(size) / unit
+	for _, prefix := range strings.Split("KMGTP", "") {
+		if value < unit {
+			return fmt.Sprintf("%.1f %siB", value, prefix)
+		}
+		value /= unit
+	}
+
+	return fmt.Sprintf("%.1f EiB", value)
+}
+
+// formatSeconds formats seconds as "m:ss" or "h:mm:ss"
+func formatSeconds(seconds float64) string {
+	total := int(seconds)
+	hours, minutes, secs := total/3600, total/60%60, total%60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, secs)
+	}
+
+	return fmt.Sprintf("%d:%02d", minutes, secs)
+}
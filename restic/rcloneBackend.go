@@ -0,0 +1,136 @@
+package restic
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	rcloneConfigEnv     string = "RCLONE_CONFIG"
+	rcloneDefaultConfig string = ".config/rclone/rclone.conf"
+)
+
+var (
+	ErrRcloneConfigNotFound = errors.New("rclone config file not found")
+)
+
+func init() {
+	RegisterBackend("rclone", NewBackendFactory(func() BackendConfig { return &RcloneBackupConfig{} }))
+}
+
+type RcloneBackupConfig struct {
+	Remote        string   `yaml:"remote"`
+	Path          string   `yaml:"path"`
+	RcloneProgram string   `yaml:"rcloneProgram"`
+	RcloneArgs    []string `yaml:"rcloneArgs"`
+	RcloneConfig  string   `yaml:"rcloneConfig"`
+	BackupSource  `yaml:",inline"`
+}
+
+func (c RcloneBackupConfig) Validate() error {
+	if c.Remote == "" {
+		return errors.New("rclone config: remote is required")
+	}
+
+	return nil
+}
+
+func (c RcloneBackupConfig) String() string {
+	var builder strings.Builder
+	builder.WriteString(srcDestString(c.Sources, fmt.Sprintf("%s:%s", c.Remote, c.Path)))
+	if c.RcloneProgram != "" {
+		builder.WriteString(fmt.Sprintf("Rclone Program: %s\n", c.RcloneProgram))
+	}
+	if len(c.RcloneArgs) > 0 {
+		builder.WriteString(fmt.Sprintf("Rclone Args: %s\n", strings.Join(c.RcloneArgs, " ")))
+	}
+	if c.RcloneConfig != "" {
+		builder.WriteString(fmt.Sprintf("Rclone Config: %s\n", c.RcloneConfig))
+	}
+
+	return builder.String()
+}
+
+// Backend returns rclone repository in format rclone:remote:path
+func (c RcloneBackupConfig) Backend() Backend {
+	backend := Backend{
+		Type:       "rclone",
+		Repository: fmt.Sprintf("rclone:%s:%s", strings.TrimSuffix(c.Remote, ":"), c.Path),
+		Preflight:  c.checkRemote,
+	}
+	if c.RcloneProgram != "" {
+		backend.Options = append(backend.Options, fmt.Sprintf("rclone.program=%s", c.RcloneProgram))
+	}
+	if len(c.RcloneArgs) > 0 {
+		backend.Options = append(backend.Options, fmt.Sprintf("rclone.args=%s", strings.Join(c.RcloneArgs, " ")))
+	}
+	if c.RcloneConfig != "" {
+		backend.Envs = append(backend.Envs, envPair(rcloneConfigEnv, c.RcloneConfig))
+	}
+
+	return backend
+}
+
+// checkRemote verifies that Remote is defined in rclone config file
+func (c RcloneBackupConfig) checkRemote() error {
+	foundRemote, err := checkRcloneRemote(c.RcloneConfig, c.Remote)
+	if errors.Is(err, ErrRcloneConfigNotFound) {
+		return &HintError{Err: err, Hint: "create rclone config with 'rclone config' or set rcloneConfig"}
+	}
+	if err != nil {
+		return err
+	}
+	if !foundRemote {
+		return &HintError{
+			Err:  fmt.Errorf("remote %s not found in rclone config file", c.Remote),
+			Hint: "add remote with 'rclone config' or check rcloneConfig setting",
+		}
+	}
+
+	return nil
+}
+
+// checkRcloneRemote find if remote is set in rclone config file with syntax '[remote]'.
+// Default rclone config location is used if configFile is empty.
+// On the fly remotes starting with ':' (e.g. ':local:') need no config and always return true
+func checkRcloneRemote(configFile, remote string) (bool, error) {
+	remote = strings.TrimSuffix(remote, ":")
+	if strings.HasPrefix(remote, ":") {
+		return true, nil
+	}
+
+	if configFile == "" {
+		configFile = os.Getenv(rcloneConfigEnv)
+	}
+	if configFile == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return false, fmt.Errorf("check rclone remote: %w", err)
+		}
+		configFile = filepath.Join(home, rcloneDefaultConfig)
+	}
+	if _, err := os.Stat(configFile); errors.Is(err, fs.ErrNotExist) {
+		return false, ErrRcloneConfigNotFound
+	}
+
+	rcloneConfigFile, err := os.Open(configFile)
+	if err != nil {
+		return false, fmt.Errorf("check rclone remote: open file: %w", err)
+	}
+	defer rcloneConfigFile.Close()
+
+	searchRemote := fmt.Sprintf("[%s]", remote)
+	scanner := bufio.NewScanner(rcloneConfigFile)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == searchRemote {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
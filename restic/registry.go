@@ -0,0 +1,126 @@
+package restic
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/liuminhaw/wrestic-bkp/restic/tests"
+	"gopkg.in/yaml.v3"
+)
+
+// BackupType is created by BackendFactory from a single backup config
+type BackupType struct {
+	// Config is the decoded type config, shown by 'config show' command
+	Config BackupTypeConfig
+	// Repository runs restic operations of the backup
+	Repository ResticRepository
+	// Test generates and cleans testing files, nil if not supported
+	Test tests.ResticTest
+}
+
+// BackupSettings holds settings from config file shared by every backup type
+type BackupSettings struct {
+	// Repository holds the repository wide settings
+	Repository ConfigRepository
+	// Run holds timeout and retry settings of the backup
+	Run RunSettings
+	// Runner runs restic program set in config
+	Runner Runner
+	// Logger logs restic runs
+	Logger *slog.Logger
+}
+
+// BackendFactory decodes config node of a backup and creates its BackupType
+type BackendFactory func(node *yaml.Node, settings BackupSettings) (BackupType, error)
+
+// BackendConfig is a BackupTypeConfig that runs on the shared BackupRepository
+type BackendConfig interface {
+	BackupTypeConfig
+	// Backend describes how restic reaches the repository
+	Backend() Backend
+	// Source returns the backup source settings
+	Source() BackupSource
+}
+
+// testableConfig is implemented by BackendConfig supporting 'test' commands
+type testableConfig interface {
+	test(repository ConfigRepository) tests.ResticTest
+}
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]BackendFactory{}
+)
+
+// RegisterBackend makes backup type name available in config file,
+// using factory to create the backup. It is meant to be called from init
+// functions and panics if name is registered twice or factory is nil
+func RegisterBackend(name string, factory BackendFactory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if factory == nil {
+		panic(fmt.Sprintf("restic: backend %s registered with nil factory", name))
+	}
+	if _, ok := backends[name]; ok {
+		panic(fmt.Sprintf("restic: backend %s registered twice", name))
+	}
+	backends[name] = factory
+}
+
+// Backends returns sorted names of registered backup types
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	names := []string{}
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func lookupBackend(name string) (BackendFactory, bool) {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	factory, ok := backends[name]
+	return factory, ok
+}
+
+// NewBackendFactory returns BackendFactory decoding config node into the
+// BackendConfig made by newConfig, validating it, and running it on BackupRepository
+func NewBackendFactory(newConfig func() BackendConfig) BackendFactory {
+	return func(node *yaml.Node, settings BackupSettings) (BackupType, error) {
+		typedConfig := newConfig()
+		if err := node.Decode(typedConfig); err != nil {
+			return BackupType{}, fmt.Errorf("decode config: %w", err)
+		}
+		if err := typedConfig.Validate(); err != nil {
+			return BackupType{}, err
+		}
+
+		backupType := BackupType{
+			Config: typedConfig,
+			Repository: BackupRepository{
+				Password:        settings.Repository.Password,
+				PasswordFile:    settings.Repository.PasswordFile,
+				PasswordCommand: settings.Repository.PasswordCommand,
+				Source:          typedConfig.Source(),
+				Backend:         typedConfig.Backend(),
+				Run:             settings.Run,
+				Runner:          settings.Runner,
+				Logger:          settings.Logger,
+			},
+		}
+		if testable, ok := typedConfig.(testableConfig); ok {
+			backupType.Test = testable.test(settings.Repository)
+		}
+
+		return backupType, nil
+	}
+}
@@ -0,0 +1,69 @@
+package restic
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/liuminhaw/wrestic-bkp/restic/tests"
+	"gopkg.in/yaml.v3"
+)
+
+func TestNewBackendFactoryValidate(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	tests := []struct {
+		name          string
+		backupType    string
+		config        string
+		wantErr       bool
+		wantPreflight bool
+	}{
+		{"gs missing project", "gs", "bucket: b", true, false},
+		{"gs missing credentials file", "gs", "projectId: p\nbucket: b\ncredentialsFile: " + missing, false, true},
+		{"rclone missing remote setting", "rclone", "path: /backup", true, false},
+		{"rclone remote not configured", "rclone", "remote: missing\nrcloneConfig: " + missing, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var node yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.config), &node); err != nil {
+				t.Fatal(err)
+			}
+			factory, _ := lookupBackend(tt.backupType)
+
+			backupType, err := factory(node.Content[0], BackupSettings{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("factory error = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			repo := backupType.Repository.(BackupRepository)
+			if err := repo.preflight(); (err != nil) != tt.wantPreflight {
+				t.Errorf("preflight error = %v, want error %t", err, tt.wantPreflight)
+			}
+		})
+	}
+}
+
+func TestNewBackendFactoryTestPassword(t *testing.T) {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte("destination: /repo"), &node); err != nil {
+		t.Fatal(err)
+	}
+	factory, _ := lookupBackend("local")
+	settings := BackupSettings{Repository: ConfigRepository{PasswordFile: "/etc/restic/password"}}
+
+	backupType, err := factory(node.Content[0], settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := backupType.Test.(tests.LocalRepositoryTest)
+	if !ok {
+		t.Fatalf("Test = %T, want tests.LocalRepositoryTest", backupType.Test)
+	}
+	if got.PasswordFile != "/etc/restic/password" || got.Password != "" || got.PasswordCommand != "" {
+		t.Errorf("Test password settings = %+v, want password file of repository", got)
+	}
+}
@@ -1,90 +1,165 @@
 package restic
 
 import (
-	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
-	"regexp"
+	"strings"
+	"time"
 )
 
 const (
 	resticCmd              string = "restic"
 	passwordEnv            string = "RESTIC_PASSWORD"
+	passwordFileEnv        string = "RESTIC_PASSWORD_FILE"
+	passwordCommandEnv     string = "RESTIC_PASSWORD_COMMAND"
 	resticProgressFPS      string = "RESTIC_PROGRESS_FPS"
 	resticProgressFPSValue string = "2"
+
+	// interruptGracePeriod is how long restic is given to clean up
+	// after SIGINT before being killed
+	interruptGracePeriod time.Duration = 30 * time.Second
 )
 
+// stdoutTerminal reports whether stdout is a terminal, restic progress is
+// only shown and redrawn in place on terminal
+var stdoutTerminal = isTerminal(os.Stdout)
+
+var (
+	ErrInterrupted = errors.New("restic interrupted")
+	ErrTimeout     = errors.New("restic timed out")
+	// ErrNoBackupSummary is returned if restic backup ends without summary message
+	ErrNoBackupSummary = errors.New("restic backup summary not found")
+)
+
+// ResticRepository runs restic operations, each stops restic when ctx is done
 type ResticRepository interface {
-	Init() ([]byte, error)
-	Backup() error
-	Snapshots() ([]byte, error)
-	Check() error
+	Init(ctx context.Context) ([]byte, error)
+	Backup(ctx context.Context) (BackupSummary, error)
+	Snapshots(ctx context.Context) ([]byte, error)
+	Check(ctx context.Context, opts CheckOptions) error
 }
 
-func execOutput(cmdArgs []string) ([]byte, error) {
-	var stderr bytes.Buffer
-	cmd := exec.Command(resticCmd, cmdArgs...)
-	cmd.Stderr = &stderr
-	output, err := cmd.Output()
+// newCommand creates restic command running binary with cmdArgs and envs appended
+// to the current environment. When ctx is done, restic receives SIGINT to release
+// its locks and is killed if still running after interruptGracePeriod
+func newCommand(ctx context.Context, binary string, cmdArgs []string, envs []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, binary, cmdArgs...)
+	cmd.Env = commandEnv(envs)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = interruptGracePeriod
+
+	return cmd
+}
+
+// commandError wraps err from running restic, marking it with ErrTimeout or
+// ErrInterrupted if restic was stopped by ctx, or as classified CommandError otherwise
+func commandError(ctx context.Context, prefix string, stderr []byte, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%s: %w: %w", prefix, ErrTimeout, err)
+	case ctx.Err() != nil:
+		return fmt.Errorf("%s: %w: %w", prefix, ErrInterrupted, err)
+	}
+
+	return fmt.Errorf("%s: %w", prefix, newCommandError(stderr, err))
+}
+
+// execOutput runs restic command with cmdArgs and returns its output.
+// Repository password and backend environments are set for this invocation only
+func (r BackupRepository) execOutput(ctx context.Context, cmdArgs []string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	r.logger().Debug("run restic", "args", cmdArgs)
+	err := r.runner().Run(ctx, Command{
+		Args:   cmdArgs,
+		Env:    r.envs(),
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
 	if err != nil {
-		return stderr.Bytes(), fmt.Errorf("execOutput: %w", err)
+		return stderr.Bytes(), commandError(ctx, "execOutput", stderr.Bytes(), err)
 	}
 
-	return output, nil
+	return stdout.Bytes(), nil
+}
+
+// execStream runs restic command with cmdArgs and stream output lines to stdout.
+// Repository password and backend environments are set for this invocation only
+func (r BackupRepository) execStream(ctx context.Context, cmdArgs []string) error {
+	return r.execLines(ctx, "execStream", cmdArgs, func(line string) {
+		fmt.Println(line)
+	})
+}
+
+// execJSON runs restic command with cmdArgs and "--json" option, calling
+// handle with every line of its output stream.
+// Repository password and backend environments are set for this invocation only
+func (r BackupRepository) execJSON(ctx context.Context, cmdArgs []string, handle func(line string)) error {
+	cmdArgs = append(cmdArgs, "--json")
+	return r.execLines(ctx, "execJSON", cmdArgs, handle)
 }
 
-// execStream runs restic command with cmdArgs and stream output to stdout
-// useLinesCount determines if cleaning screen operation will clean only the line match regex pattern
-// or all lines before match regex pattern. Set to true for all lines cleaning
-// and false for matched line clean
-func execStream(cmdArgs []string, useLinesCount bool) error {
+// execLines runs restic command with cmdArgs, calling fn with every line of its output.
+// Restic error output is logged if command fails
+func (r BackupRepository) execLines(ctx context.Context, prefix string, cmdArgs []string, fn func(line string)) error {
+	stdout := &lineWriter{fn: fn}
+	err := r.execWriter(ctx, prefix, cmdArgs, stdout)
+	stdout.Flush()
+
+	return err
+}
+
+// execWriter runs restic command with cmdArgs, writing its output to stdout.
+// Restic error output is logged if command fails
+func (r BackupRepository) execWriter(ctx context.Context, prefix string, cmdArgs []string, stdout io.Writer) error {
 	var stderr bytes.Buffer
-	cmd := exec.Command(resticCmd, cmdArgs...)
-	cmd.Stderr = &stderr
 
-	stdout, err := cmd.StdoutPipe()
+	r.logger().Debug("run restic", "args", cmdArgs)
+	err := r.runner().Run(ctx, Command{
+		Args:   cmdArgs,
+		Env:    r.envs(),
+		Stdout: stdout,
+		Stderr: &stderr,
+	})
 	if err != nil {
-		return fmt.Errorf("execStream: %w", err)
+		if stderr.Len() > 0 {
+			r.logger().Error("restic failed", "command", cmdArgs[0], "stderr", strings.TrimSpace(stderr.String()))
+		}
+		return commandError(ctx, prefix, stderr.Bytes(), err)
 	}
 
-	// Start command
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("execStream: command start: %w", err)
-	}
+	return nil
+}
 
-	// Read from the pipe
-	linesCount := 0
-	if !useLinesCount {
-		linesCount = 1
-	}
-	scanner := bufio.NewScanner(stdout)
-	for scanner.Scan() {
-		str := scanner.Text()
-		// Check if string start with pattern `[x:xx]`
-		pattern := regexp.MustCompile(`^\[\d+:\d\d\]`)
-		if pattern.MatchString(str) {
-			for i := 0; i < linesCount; i++ {
-				fmt.Print("\033[A")
-			}
-			fmt.Print("\033[K")
-			if useLinesCount {
-				linesCount = 0
-			}
-		}
-		fmt.Println(str) // Print each line of output
-		if useLinesCount {
-			linesCount++
-		}
+// commandEnv returns the environment for a restic invocation with envs
+// in "KEY=value" form appended to the current process environment
+func commandEnv(envs []string) []string {
+	if len(envs) == 0 {
+		return nil
 	}
 
-	// Wait for the command to finish
-	if err := cmd.Wait(); err != nil {
-		fmt.Println(stderr.String())
-		return fmt.Errorf("execStream: command wait: %w", err)
+	return append(os.Environ(), envs...)
+}
+
+// envPair formats key and value as a "KEY=value" environment entry
+func envPair(key, value string) string {
+	return fmt.Sprintf("%s=%s", key, value)
+}
+
+// isTerminal reports whether file is a character device such as terminal
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
 	}
 
-	return nil
+	return info.Mode()&os.ModeCharDevice != 0
 }
 
 func countStringLines(s string) int {
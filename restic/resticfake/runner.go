@@ -0,0 +1,129 @@
+// Package resticfake provides a restic.Runner recording invocations and
+// returning scripted results, for running repositories without restic installed
+package resticfake
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/liuminhaw/wrestic-bkp/restic"
+)
+
+// Call is a restic invocation recorded by Runner
+type Call struct {
+	Args  []string
+	Env   []string
+	Stdin []byte
+}
+
+// CommandLine returns Args joined by space, e.g. "backup -r /repo /src"
+func (c Call) CommandLine() string {
+	return strings.Join(c.Args, " ")
+}
+
+// Getenv returns value of key set in Env, empty string if not set
+func (c Call) Getenv(key string) string {
+	value := ""
+	for _, env := range c.Env {
+		if k, v, ok := strings.Cut(env, "="); ok && k == key {
+			value = v
+		}
+	}
+
+	return value
+}
+
+// Response is scripted result of a restic invocation
+type Response struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// ExitError is returned by Runner for responses with non zero ExitCode
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// Runner records every restic invocation and replies with Responses in order.
+// Handler, if set, is used instead of Responses. Invocations beyond
+// scripted responses succeed with empty output
+type Runner struct {
+	Responses []Response
+	Handler   func(call Call) Response
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// New returns Runner replying with responses in order
+func New(responses ...Response) *Runner {
+	return &Runner{Responses: responses}
+}
+
+func (r *Runner) Run(ctx context.Context, command restic.Command) error {
+	call := Call{
+		Args: append([]string{}, command.Args...),
+		Env:  append([]string{}, command.Env...),
+	}
+	if command.Stdin != nil {
+		stdin, err := io.ReadAll(command.Stdin)
+		if err != nil {
+			return fmt.Errorf("fake runner: read stdin: %w", err)
+		}
+		call.Stdin = stdin
+	}
+
+	response := r.record(call)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if command.Stdout != nil {
+		io.WriteString(command.Stdout, response.Stdout)
+	}
+	if command.Stderr != nil {
+		io.WriteString(command.Stderr, response.Stderr)
+	}
+	if response.ExitCode != 0 {
+		return &ExitError{Code: response.ExitCode}
+	}
+
+	return nil
+}
+
+// Calls returns recorded invocations in order
+func (r *Runner) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Call{}, r.calls...)
+}
+
+// record saves call and returns its scripted response
+func (r *Runner) record(call Call) Response {
+	r.mu.Lock()
+	index := len(r.calls)
+	r.calls = append(r.calls, call)
+	r.mu.Unlock()
+
+	switch {
+	case r.Handler != nil:
+		return r.Handler(call)
+	case index < len(r.Responses):
+		return r.Responses[index]
+	default:
+		return Response{}
+	}
+}
@@ -0,0 +1,208 @@
+package restic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	defaultRetryBackoff    time.Duration = 10 * time.Second
+	defaultRetryMaxBackoff time.Duration = 5 * time.Minute
+)
+
+// Operation names used as keys of RunSettings.Operations
+const (
+	OperationInit      string = "init"
+	OperationBackup    string = "backup"
+	OperationSnapshots string = "snapshots"
+	OperationCheck     string = "check"
+	OperationDiff      string = "diff"
+	OperationLs        string = "ls"
+	OperationFind      string = "find"
+	OperationDump      string = "dump"
+	OperationCopy      string = "copy"
+	OperationKey       string = "key"
+	OperationStats     string = "stats"
+	OperationTag       string = "tag"
+	OperationRewrite   string = "rewrite"
+	OperationPrune     string = "prune"
+	OperationRepair    string = "repair"
+	OperationMigrate   string = "migrate"
+	OperationVerify    string = "verify"
+	OperationDoctor    string = "doctor"
+)
+
+// errNoRetry marks failure of operation that should not be retried,
+// e.g. after its output is partially written
+var errNoRetry = errors.New("not retried")
+
+// RunSettings controls how restic operations of a backup run.
+// Settings in Operations override backup wide Timeout and Retry
+type RunSettings struct {
+	Timeout    time.Duration                `yaml:"timeout,omitempty"`
+	Retry      RetryPolicy                  `yaml:"retry,omitempty"`
+	Operations map[string]OperationSettings `yaml:"operations,omitempty"`
+	// AutoUnlockStale removes stale locks left by this host before each operation
+	AutoUnlockStale bool `yaml:"autoUnlockStale,omitempty"`
+	// Check controls repository check after backup and its data subset
+	Check CheckSettings `yaml:"check,omitempty"`
+	// Verify controls restore drill after backup and files it restores
+	Verify VerifySettings `yaml:"verify,omitempty"`
+	// CopyTo are names of backups whose repositories snapshots are copied to after backup
+	CopyTo []string `yaml:"copyTo,omitempty"`
+}
+
+// OperationSettings overrides RunSettings for single operation
+type OperationSettings struct {
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	Retry   *RetryPolicy  `yaml:"retry,omitempty"`
+}
+
+// RetryPolicy sets how many times an operation is attempted on transient
+// failures, waiting Backoff doubled on each attempt up to MaxBackoff in between
+type RetryPolicy struct {
+	Attempts   int           `yaml:"attempts,omitempty"`
+	Backoff    time.Duration `yaml:"backoff,omitempty"`
+	MaxBackoff time.Duration `yaml:"maxBackoff,omitempty"`
+}
+
+// Validate checks that operation names and values are valid
+func (s RunSettings) Validate() error {
+	if s.Timeout < 0 {
+		return fmt.Errorf("timeout should not be negative: %s", s.Timeout)
+	}
+	if err := s.Retry.Validate(); err != nil {
+		return err
+	}
+	if err := s.Check.Validate(); err != nil {
+		return err
+	}
+	if err := s.Verify.Validate(); err != nil {
+		return err
+	}
+	for name, operation := range s.Operations {
+		if !isOperation(name) {
+			return fmt.Errorf("unknown operation %s", name)
+		}
+		if operation.Timeout < 0 {
+			return fmt.Errorf("operation %s: timeout should not be negative: %s", name, operation.Timeout)
+		}
+		if operation.Retry != nil {
+			if err := operation.Retry.Validate(); err != nil {
+				return fmt.Errorf("operation %s: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// operation returns timeout and retry policy in effect for operation name
+func (s RunSettings) operation(name string) (time.Duration, RetryPolicy) {
+	timeout, retry := s.Timeout, s.Retry
+	if operation, ok := s.Operations[name]; ok {
+		if operation.Timeout != 0 {
+			timeout = operation.Timeout
+		}
+		if operation.Retry != nil {
+			retry = *operation.Retry
+		}
+	}
+
+	return timeout, retry
+}
+
+func (p RetryPolicy) Validate() error {
+	if p.Attempts < 0 {
+		return fmt.Errorf("retry attempts should not be negative: %d", p.Attempts)
+	}
+	if p.Backoff < 0 || p.MaxBackoff < 0 {
+		return fmt.Errorf("retry backoff should not be negative")
+	}
+
+	return nil
+}
+
+// attempts returns total attempts, at least one
+func (p RetryPolicy) attempts() int {
+	if p.Attempts < 1 {
+		return 1
+	}
+
+	return p.Attempts
+}
+
+// backoff returns wait duration after failed attempt, counting from 1
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff, maxBackoff := p.Backoff, p.MaxBackoff
+	if backoff == 0 {
+		backoff = defaultRetryBackoff
+	}
+	if maxBackoff == 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+
+	return backoff
+}
+
+func isOperation(name string) bool {
+	switch name {
+	case OperationInit, OperationBackup, OperationSnapshots, OperationCheck,
+		OperationDiff, OperationLs, OperationFind, OperationDump, OperationCopy,
+		OperationKey, OperationStats, OperationTag, OperationRewrite, OperationPrune,
+		OperationRepair, OperationMigrate, OperationVerify, OperationDoctor:
+		return true
+	}
+
+	return false
+}
+
+// runOperation runs fn within operation timeout of settings, retrying while
+// fn fails with transient restic failure and attempts are left
+func runOperation(ctx context.Context, logger *slog.Logger, settings RunSettings, operation string, fn func(ctx context.Context) error) error {
+	timeout, retry := settings.operation(operation)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	attempts := retry.attempts()
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		kind := failureKind(err)
+		if attempt >= attempts || !kind.Transient() || errors.Is(err, errNoRetry) {
+			return err
+		}
+
+		backoff := retry.backoff(attempt)
+		logger.Warn("restic attempt failed",
+			"operation", operation,
+			"attempt", attempt,
+			"attempts", attempts,
+			"failure", kind.String(),
+			"error", err,
+			"retryIn", backoff)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return commandError(ctx, operation, nil, err)
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package restic_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/liuminhaw/wrestic-bkp/restic/resticfake"
+)
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// fastRetry retries without waiting noticeably between attempts
+var fastRetry = restic.RetryPolicy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+func fakeRepository(runner restic.Runner, run restic.RunSettings) restic.BackupRepository {
+	return restic.BackupRepository{
+		Password: "secret",
+		Backend:  restic.Backend{Type: "local", Repository: "/repo"},
+		Run:      run,
+		Runner:   runner,
+		Logger:   discardLogger,
+	}
+}
+
+func TestRetryScriptedRestic(t *testing.T) {
+	networkFailure := resticfake.Response{ExitCode: 1, Stderr: "Fatal: read tcp: connection reset by peer"}
+	lockedFailure := resticfake.Response{ExitCode: 11, Stderr: "repository is already locked"}
+	authFailure := resticfake.Response{ExitCode: 12, Stderr: "Fatal: wrong password or no key found"}
+	unknownFailure := resticfake.Response{ExitCode: 1, Stderr: "Fatal: invalid argument"}
+	success := resticfake.Response{Stdout: "[]"}
+
+	tests := []struct {
+		name      string
+		retry     restic.RetryPolicy
+		responses []resticfake.Response
+		wantCalls int
+		wantKind  restic.FailureKind
+		wantErr   bool
+	}{
+		{"success", fastRetry, []resticfake.Response{success}, 1, restic.FailureUnknown, false},
+		{"network then success", fastRetry, []resticfake.Response{networkFailure, success}, 2, restic.FailureUnknown, false},
+		{"locked then success", fastRetry, []resticfake.Response{lockedFailure, lockedFailure, success}, 3, restic.FailureUnknown, false},
+		{"attempts exhausted", fastRetry, []resticfake.Response{networkFailure, networkFailure, networkFailure, success}, 3, restic.FailureNetwork, true},
+		{"no retry by default", restic.RetryPolicy{}, []resticfake.Response{networkFailure, success}, 1, restic.FailureNetwork, true},
+		{"auth not retried", fastRetry, []resticfake.Response{authFailure, success}, 1, restic.FailureAuth, true},
+		{"unknown not retried", fastRetry, []resticfake.Response{unknownFailure, success}, 1, restic.FailureUnknown, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := resticfake.New(tt.responses...)
+			repo := fakeRepository(fake, restic.RunSettings{Retry: tt.retry})
+
+			_, err := repo.Snapshots(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Snapshots() error = %v, want error %t", err, tt.wantErr)
+			}
+			if calls := len(fake.Calls()); calls != tt.wantCalls {
+				t.Errorf("restic called %d times, want %d", calls, tt.wantCalls)
+			}
+			if !tt.wantErr {
+				return
+			}
+			var cmdErr *restic.CommandError
+			if !errors.As(err, &cmdErr) {
+				t.Fatalf("Snapshots() error = %v, want CommandError", err)
+			}
+			if cmdErr.Kind != tt.wantKind {
+				t.Errorf("failure kind = %s, want %s", cmdErr.Kind, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestRetryOperationOverride(t *testing.T) {
+	networkFailure := resticfake.Response{ExitCode: 1, Stderr: "dial tcp: i/o timeout"}
+	fake := resticfake.New(networkFailure, networkFailure, networkFailure, networkFailure)
+	noRetry := restic.RetryPolicy{Attempts: 1}
+	repo := fakeRepository(fake, restic.RunSettings{
+		Retry:      fastRetry,
+		Operations: map[string]restic.OperationSettings{restic.OperationSnapshots: {Retry: &noRetry}},
+	})
+
+	if _, err := repo.Snapshots(context.Background()); err == nil {
+		t.Fatal("Snapshots() succeeded, want error")
+	}
+	if calls := len(fake.Calls()); calls != 1 {
+		t.Errorf("restic called %d times, want 1", calls)
+	}
+}
+
+func TestRetryTimeout(t *testing.T) {
+	fake := &resticfake.Runner{Handler: func(call resticfake.Call) resticfake.Response {
+		time.Sleep(50 * time.Millisecond)
+		return resticfake.Response{}
+	}}
+	repo := fakeRepository(fake, restic.RunSettings{Timeout: 10 * time.Millisecond, Retry: fastRetry})
+
+	_, err := repo.Snapshots(context.Background())
+	if !errors.Is(err, restic.ErrTimeout) {
+		t.Errorf("Snapshots() error = %v, want ErrTimeout", err)
+	}
+	if calls := len(fake.Calls()); calls != 1 {
+		t.Errorf("restic called %d times, want 1", calls)
+	}
+}
+
+func TestRetryInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fake := &resticfake.Runner{Handler: func(call resticfake.Call) resticfake.Response {
+		cancel()
+		return resticfake.Response{}
+	}}
+	repo := fakeRepository(fake, restic.RunSettings{Retry: fastRetry})
+
+	_, err := repo.Snapshots(ctx)
+	if !errors.Is(err, restic.ErrInterrupted) {
+		t.Errorf("Snapshots() error = %v, want ErrInterrupted", err)
+	}
+}
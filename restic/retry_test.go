@@ -0,0 +1,130 @@
+package restic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{"default first", RetryPolicy{}, 1, defaultRetryBackoff},
+		{"default doubled", RetryPolicy{}, 2, 2 * defaultRetryBackoff},
+		{"default capped", RetryPolicy{}, 10, defaultRetryMaxBackoff},
+		{"first", RetryPolicy{Backoff: time.Second, MaxBackoff: time.Minute}, 1, time.Second},
+		{"third", RetryPolicy{Backoff: time.Second, MaxBackoff: time.Minute}, 3, 4 * time.Second},
+		{"capped at max", RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}, 4, 5 * time.Second},
+		{"backoff above max", RetryPolicy{Backoff: time.Minute, MaxBackoff: time.Second}, 1, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.backoff(tt.attempt); got != tt.want {
+				t.Errorf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunSettingsOperation(t *testing.T) {
+	operationRetry := RetryPolicy{Attempts: 5}
+	settings := RunSettings{
+		Timeout: time.Hour,
+		Retry:   RetryPolicy{Attempts: 2},
+		Operations: map[string]OperationSettings{
+			OperationCheck: {Timeout: time.Minute, Retry: &operationRetry},
+			OperationLs:    {Timeout: time.Second},
+		},
+	}
+
+	tests := []struct {
+		operation    string
+		wantTimeout  time.Duration
+		wantAttempts int
+	}{
+		{OperationBackup, time.Hour, 2},
+		{OperationCheck, time.Minute, 5},
+		{OperationLs, time.Second, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.operation, func(t *testing.T) {
+			timeout, retry := settings.operation(tt.operation)
+			if timeout != tt.wantTimeout || retry.attempts() != tt.wantAttempts {
+				t.Errorf("operation(%s) = %s, %d attempts, want %s, %d attempts",
+					tt.operation, timeout, retry.attempts(), tt.wantTimeout, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRunOperation(t *testing.T) {
+	networkErr := newCommandError([]byte("connection reset by peer"), exitCodeError(1))
+	authErr := newCommandError(nil, exitCodeError(12))
+	retry := RetryPolicy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+	tests := []struct {
+		name      string
+		retry     RetryPolicy
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{"success", retry, nil, 1, nil},
+		{"transient then success", retry, []error{networkErr}, 2, nil},
+		{"transient until attempts left", retry, []error{networkErr, networkErr, networkErr, networkErr}, 3, networkErr},
+		{"single attempt by default", RetryPolicy{}, []error{networkErr}, 1, networkErr},
+		{"permanent not retried", retry, []error{authErr}, 1, authErr},
+		{"no retry marked", retry, []error{fmt.Errorf("%w: %w", errNoRetry, networkErr)}, 1, errNoRetry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := runOperation(context.Background(), discardLogger, RunSettings{Retry: tt.retry}, OperationBackup,
+				func(ctx context.Context) error {
+					calls++
+					if calls <= len(tt.errs) {
+						return tt.errs[calls-1]
+					}
+					return nil
+				})
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunOperationStopsBackoffOnDeadline(t *testing.T) {
+	networkErr := newCommandError([]byte("connection reset by peer"), exitCodeError(1))
+	settings := RunSettings{
+		Timeout: 20 * time.Millisecond,
+		Retry:   RetryPolicy{Attempts: 3, Backoff: time.Hour, MaxBackoff: time.Hour},
+	}
+
+	start := time.Now()
+	err := runOperation(context.Background(), discardLogger, settings, OperationBackup, func(ctx context.Context) error {
+		return networkErr
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Minute {
+		t.Errorf("runOperation waited %s for backoff after deadline", elapsed)
+	}
+}
@@ -0,0 +1,76 @@
+package restic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// RewriteOptions selects snapshots and paths excluded by Rewrite
+type RewriteOptions struct {
+	// Snapshots are IDs of rewritten snapshots, all snapshots if empty
+	Snapshots []string
+	// Excludes are patterns of paths removed from snapshots
+	Excludes []string
+	// Forget removes original snapshots after rewrite, their data is
+	// removed from repository by prune
+	Forget bool
+}
+
+// Rewriter is implemented by repositories removing paths from snapshots.
+// Without apply, rewrite is a dry run only listing the changes
+type Rewriter interface {
+	Rewrite(ctx context.Context, opts RewriteOptions, apply bool) error
+}
+
+// Validate checks that at least one exclude pattern is given
+func (o RewriteOptions) Validate() error {
+	if len(o.Excludes) == 0 {
+		return errors.New("rewrite: no exclude patterns")
+	}
+
+	return nil
+}
+
+// Rewrite saves snapshots selected by opts without excluded paths if apply
+// is set, otherwise reports what would be rewritten. Output is streamed to stdout
+func (r BackupRepository) Rewrite(ctx context.Context, opts RewriteOptions, apply bool) error {
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("%s repository rewrite: %w", r.Backend.Type, err)
+	}
+	if err := r.preflight(); err != nil {
+		return fmt.Errorf("%s repository rewrite: %w", r.Backend.Type, err)
+	}
+	if err := RequireFeature(ctx, r.runner(), FeatureRewrite); err != nil {
+		return fmt.Errorf("%s repository rewrite: %w", r.Backend.Type, err)
+	}
+	r.unlockStale(ctx)
+
+	commandArg := r.commandArgs("rewrite")
+	for _, exclude := range opts.Excludes {
+		commandArg = append(commandArg, fmt.Sprintf("--exclude=%s", exclude))
+	}
+	if opts.Forget {
+		commandArg = append(commandArg, "--forget")
+	}
+	if !apply {
+		commandArg = append(commandArg, "--dry-run")
+	}
+	commandArg = append(commandArg, opts.Snapshots...)
+
+	if apply {
+		r.logger().Info("restic rewrite", "excludes", opts.Excludes, "forget", opts.Forget)
+	}
+	err := runOperation(ctx, r.logger(), r.Run, OperationRewrite, func(ctx context.Context) error {
+		err := r.execStream(ctx, commandArg)
+		if err != nil && apply {
+			return fmt.Errorf("%w, snapshots may be partially rewritten: %w", errNoRetry, err)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s repository rewrite: %w", r.Backend.Type, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package restic
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	runLockSuffix       string        = ".lock"
+	runLockPollInterval time.Duration = time.Second
+)
+
+var (
+	ErrAlreadyRunning = errors.New("another run of backup is in progress")
+)
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// RunInfo describes a run holding RunLock, saved in its lock file
+type RunInfo struct {
+	PID        int       `json:"pid"`
+	Backup     string    `json:"backup"`
+	Repository string    `json:"repository"`
+	Operation  string    `json:"operation"`
+	Started    time.Time `json:"started"`
+}
+
+// RunLock is a local lock file preventing overlapping runs of the same
+// backup and repository on this host
+type RunLock struct {
+	file *os.File
+}
+
+// AcquireRunLock locks backup and repository of info in dir, waiting up to wait
+// for the lock to be released by another run. Return ErrAlreadyRunning error if
+// lock is still held after wait, with RunInfo of the holding run if readable
+func AcquireRunLock(ctx context.Context, dir string, info RunInfo, wait time.Duration) (*RunLock, RunInfo, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, RunInfo{}, fmt.Errorf("acquire run lock: %w", err)
+	}
+
+	path := filepath.Join(dir, runLockName(info.Backup, info.Repository))
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, RunInfo{}, fmt.Errorf("acquire run lock: %w", err)
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, RunInfo{}, fmt.Errorf("acquire run lock: %w", err)
+		}
+		if locked {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			holder, _ := readRunInfo(path)
+			file.Close()
+			return nil, holder, ErrAlreadyRunning
+		}
+
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, RunInfo{}, fmt.Errorf("acquire run lock: %w: %w", ErrInterrupted, ctx.Err())
+		case <-time.After(runLockPollInterval):
+		}
+	}
+
+	if err := writeRunInfo(file, info); err != nil {
+		unlockFile(file)
+		file.Close()
+		return nil, RunInfo{}, fmt.Errorf("acquire run lock: %w", err)
+	}
+
+	return &RunLock{file: file}, info, nil
+}
+
+// Release clears run info and unlocks the lock file
+func (l *RunLock) Release() error {
+	if err := l.file.Truncate(0); err != nil {
+		return fmt.Errorf("release run lock: %w", err)
+	}
+	if err := unlockFile(l.file); err != nil {
+		return fmt.Errorf("release run lock: %w", err)
+	}
+
+	return l.file.Close()
+}
+
+// RunningRuns returns runs recorded in lock files of dir whose process is
+// still alive, oldest first. Lock files are only read, so probing never
+// contends with a run acquiring its lock
+func RunningRuns(dir string) ([]RunInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("running runs: %w", err)
+	}
+
+	runs := []RunInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), runLockSuffix) {
+			continue
+		}
+
+		// Released locks are truncated, locks left by crashed runs name dead process
+		info, err := readRunInfo(filepath.Join(dir, entry.Name()))
+		if err != nil || !processAlive(info.PID) {
+			continue
+		}
+		runs = append(runs, info)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Started.Before(runs[j].Started) })
+
+	return runs, nil
+}
+
+// DefaultRuntimeDir returns directory for run locks, /run/wrestic-bkp for root,
+// $XDG_RUNTIME_DIR/wrestic-bkp or temporary directory for other users
+func DefaultRuntimeDir() string {
+	if os.Geteuid() == 0 {
+		return "/run/wrestic-bkp"
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "wrestic-bkp")
+	}
+
+	return filepath.Join(os.TempDir(), fmt.Sprintf("wrestic-bkp-%d", os.Geteuid()))
+}
+
+// runLockName returns lock file name from backup name and hash of repository
+func runLockName(backup, repository string) string {
+	sum := sha256.Sum256([]byte(repository))
+	name := unsafeNameChars.ReplaceAllString(backup, "_")
+
+	return fmt.Sprintf("%s-%x%s", name, sum[:6], runLockSuffix)
+}
+
+func writeRunInfo(file *os.File, info RunInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := file.WriteAt(data, 0); err != nil {
+		return err
+	}
+
+	return file.Sync()
+}
+
+func readRunInfo(path string) (RunInfo, error) {
+	var info RunInfo
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, err
+	}
+
+	return info, nil
+}
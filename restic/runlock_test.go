@@ -0,0 +1,50 @@
+//go:build unix
+
+package restic
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunningRuns(t *testing.T) {
+	dir := t.TempDir()
+	info := RunInfo{PID: os.Getpid(), Backup: "home", Repository: "/repo", Operation: OperationBackup, Started: time.Now()}
+
+	lock, _, err := AcquireRunLock(context.Background(), dir, info, 0)
+	if err != nil {
+		t.Fatalf("AcquireRunLock() error = %v", err)
+	}
+	// Lock file left by a crashed run
+	crashed := `{"pid":-1,"backup":"crashed","repository":"/repo","operation":"backup"}`
+	if err := os.WriteFile(filepath.Join(dir, "crashed"+runLockSuffix), []byte(crashed), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := RunningRuns(dir)
+	if err != nil {
+		t.Fatalf("RunningRuns() error = %v", err)
+	}
+	if len(runs) != 1 || runs[0].Backup != "home" {
+		t.Errorf("RunningRuns() = %+v, want run of home", runs)
+	}
+
+	// Probing must not take the lock away from the holding run
+	if _, _, err := AcquireRunLock(context.Background(), dir, info, 0); err != ErrAlreadyRunning {
+		t.Errorf("AcquireRunLock() while held error = %v, want ErrAlreadyRunning", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	runs, err = RunningRuns(dir)
+	if err != nil {
+		t.Fatalf("RunningRuns() error = %v", err)
+	}
+	if len(runs) != 0 {
+		t.Errorf("RunningRuns() after release = %+v, want none", runs)
+	}
+}
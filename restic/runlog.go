@@ -0,0 +1,62 @@
+package restic
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const runLogName string = "runs.log"
+
+// Status values of RunRecord
+const (
+	RunSucceeded   string = "succeeded"
+	RunFailed      string = "failed"
+	RunInterrupted string = "interrupted"
+)
+
+// RunRecord is a finished run of operation changing repository, appended
+// to the run log as a json line
+type RunRecord struct {
+	Backup     string `json:"backup"`
+	Repository string `json:"repository"`
+	Operation  string `json:"operation"`
+	// Args are command line arguments of the run
+	Args     []string  `json:"args"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// AppendRunRecord appends record to run log in dir
+func AppendRunRecord(dir string, record RunRecord) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("append run record: %w", err)
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("append run record: %w", err)
+	}
+
+	file, err := os.OpenFile(RunLogPath(dir), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("append run record: %w", err)
+	}
+	_, err = file.Write(append(data, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("append run record: %w", err)
+	}
+
+	return nil
+}
+
+// RunLogPath returns path of run log in dir
+func RunLogPath(dir string) string {
+	return filepath.Join(dir, runLogName)
+}
@@ -0,0 +1,77 @@
+package restic
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// Command is a single restic invocation
+type Command struct {
+	// Args are restic arguments, without the restic program itself
+	Args []string
+	// Env are "KEY=value" entries appended to the current environment
+	Env    []string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Runner runs restic commands. Errors of unsuccessful exits should
+// implement ExitCode() int, as *exec.ExitError does
+type Runner interface {
+	Run(ctx context.Context, command Command) error
+}
+
+// DefaultRunner is used by repositories without Runner set
+var DefaultRunner Runner = ExecRunner{}
+
+// ExecRunner runs restic program at Binary, "restic" from system path if empty
+type ExecRunner struct {
+	Binary string
+}
+
+func (r ExecRunner) Run(ctx context.Context, command Command) error {
+	cmd := newCommand(ctx, r.binary(), command.Args, command.Env)
+	cmd.Stdin = command.Stdin
+	cmd.Stdout = command.Stdout
+	cmd.Stderr = command.Stderr
+
+	return cmd.Run()
+}
+
+// lineWriter calls fn with every complete line written to it
+type lineWriter struct {
+	buf []byte
+	fn  func(line string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.fn(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush calls fn with remaining data not ended by newline
+func (w *lineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.fn(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (r ExecRunner) binary() string {
+	if r.Binary == "" {
+		return resticCmd
+	}
+
+	return r.Binary
+}
@@ -0,0 +1,48 @@
+package restic
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	awsAccessKeyIdEnv     string = "AWS_ACCESS_KEY_ID"
+	awsSecretAccessKeyEnv string = "AWS_SECRET_ACCESS_KEY"
+)
+
+func init() {
+	RegisterBackend("s3", NewBackendFactory(func() BackendConfig { return &S3BackupConfig{} }))
+}
+
+type S3BackupConfig struct {
+	AccessKeyId     string `yaml:"accessKeyId"`
+	SecretAccessKey string `yaml:"secretAccessKey"`
+	Region          string `yaml:"region"`
+	BackupSource    `yaml:",inline"`
+	Destination     string `yaml:"destination"`
+}
+
+func (c S3BackupConfig) Validate() error {
+	return nil
+}
+
+func (c S3BackupConfig) String() string {
+	var builder strings.Builder
+	builder.WriteString(srcDestString(c.Sources, c.Destination))
+	builder.WriteString(fmt.Sprintf("Access Key ID: %s\n", c.AccessKeyId))
+	builder.WriteString(fmt.Sprintf("Secret Access Key: %s\n", c.SecretAccessKey))
+	builder.WriteString(fmt.Sprintf("Region: %s\n", c.Region))
+
+	return builder.String()
+}
+
+func (c S3BackupConfig) Backend() Backend {
+	return Backend{
+		Type:       "s3",
+		Repository: fmt.Sprintf("s3:s3.amazonaws.com/%s", c.Destination),
+		Envs: []string{
+			envPair(awsAccessKeyIdEnv, c.AccessKeyId),
+			envPair(awsSecretAccessKeyEnv, c.SecretAccessKey),
+		},
+	}
+}
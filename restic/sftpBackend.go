@@ -0,0 +1,105 @@
+package restic
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+const sshConfigSetupHint string = `add host to ssh config file, for example:
+Host custom-config-name
+    Hostname [ip-address|domain name]
+    User username
+    Port 22
+    Identityfile /path/to/ssh/key/file
+    ServerAliveInterval 60
+    ServerAliveCountMax 240`
+
+var (
+	ErrSshConfigNotFound = errors.New("user ssh config file not found")
+)
+
+func init() {
+	RegisterBackend("sftp", NewBackendFactory(func() BackendConfig { return &SftpBackupConfig{} }))
+}
+
+type SftpBackupConfig struct {
+	Host         string `yaml:"host"`
+	BackupSource `yaml:",inline"`
+	Destination  string `yaml:"destination"`
+}
+
+func (c SftpBackupConfig) Validate() error {
+	return nil
+}
+
+func (c SftpBackupConfig) String() string {
+	var builder strings.Builder
+	builder.WriteString(srcDestString(c.Sources, c.Destination))
+	builder.WriteString(fmt.Sprintf("Host: %s\n", c.Host))
+
+	return builder.String()
+}
+
+func (c SftpBackupConfig) Backend() Backend {
+	return Backend{
+		Type:       "sftp",
+		Repository: fmt.Sprintf("sftp:%s:%s", c.Host, c.Destination),
+		Preflight:  c.checkHost,
+	}
+}
+
+// checkHost checks if Host setting exist in ssh config file
+func (c SftpBackupConfig) checkHost() error {
+	foundHost, err := checkSshHost(c.Host)
+	if errors.Is(err, ErrSshConfigNotFound) {
+		return &HintError{Err: err, Hint: sshConfigSetupHint}
+	}
+	if err != nil {
+		return err
+	}
+	if !foundHost {
+		return &HintError{
+			Err:  fmt.Errorf("host setting %s not found in ssh config file", c.Host),
+			Hint: sshConfigSetupHint,
+		}
+	}
+
+	return nil
+}
+
+// checkSshHost find if configHost is set in user's ssh config file with syntax 'Host configHost'
+// Return true is found, and return false otherwise
+func checkSshHost(configHost string) (bool, error) {
+	// Get user's home directory
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false, fmt.Errorf("check ssh host: %w", err)
+	}
+	// Check ssh config file existence
+	sshConfig := fmt.Sprintf("%s/.ssh/config", home)
+	if _, err := os.Stat(sshConfig); errors.Is(err, fs.ErrNotExist) {
+		return false, ErrSshConfigNotFound
+	}
+
+	// Check if input configHost exist in ssh config file
+	searchHost := fmt.Sprintf("Host %s", configHost)
+	sshConfigFile, err := os.Open(sshConfig)
+	if err != nil {
+		return false, fmt.Errorf("check ssh host: open file: %w", err)
+	}
+	defer sshConfigFile.Close()
+
+	scanner := bufio.NewScanner(sshConfigFile)
+	for scanner.Scan() {
+		// line := scanner.Text()
+		if strings.Contains(scanner.Text(), searchHost) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,221 @@
+package restic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const latestSnapshot string = "latest"
+
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Snapshot is a snapshot listed by restic snapshots --json
+type Snapshot struct {
+	ID       string    `json:"id"`
+	ShortID  string    `json:"short_id"`
+	Time     time.Time `json:"time"`
+	Parent   string    `json:"parent"`
+	Hostname string    `json:"hostname"`
+	Username string    `json:"username"`
+	Paths    []string  `json:"paths"`
+	Tags     []string  `json:"tags"`
+	// Summary is set for snapshots taken by restic 0.17 or newer
+	Summary *SnapshotSummary `json:"summary,omitempty"`
+}
+
+// SnapshotSummary is backup statistics recorded in snapshot
+type SnapshotSummary struct {
+	DataAdded           uint64 `json:"data_added"`
+	DataAddedPacked     uint64 `json:"data_added_packed"`
+	TotalFilesProcessed uint64 `json:"total_files_processed"`
+	TotalBytesProcessed uint64 `json:"total_bytes_processed"`
+}
+
+// Node is a file, directory or other entry of a snapshot listed by restic ls --json
+type Node struct {
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Path        string    `json:"path"`
+	Size        uint64    `json:"size"`
+	Permissions string    `json:"permissions"`
+	ModTime     time.Time `json:"mtime"`
+}
+
+// LsOptions selects snapshot nodes listed by Ls
+type LsOptions struct {
+	// Path is directory listed, whole snapshot is listed if empty
+	Path string
+	// Recursive lists nodes under subdirectories of Path
+	Recursive bool
+}
+
+// FindOptions narrows nodes searched by Find
+type FindOptions struct {
+	// Oldest and Newest limit modification time of found nodes, e.g. "2024-05-01"
+	Oldest string
+	Newest string
+	// Paths limits search to snapshots including these paths
+	Paths []string
+}
+
+// FindMatch is a node matching Find pattern in Snapshot
+type FindMatch struct {
+	Snapshot string
+	Node
+}
+
+// Browser is implemented by repositories listing snapshots and their contents
+type Browser interface {
+	SnapshotList(ctx context.Context) ([]Snapshot, error)
+	Ls(ctx context.Context, snapshot string, opts LsOptions) ([]Node, error)
+	Find(ctx context.Context, pattern string, opts FindOptions) ([]FindMatch, error)
+}
+
+// SnapshotList returns snapshots of repository ordered from oldest to newest
+func (r BackupRepository) SnapshotList(ctx context.Context) ([]Snapshot, error) {
+	if err := r.preflight(); err != nil {
+		return nil, fmt.Errorf("%s repository snapshot list: %w", r.Backend.Type, err)
+	}
+	r.unlockStale(ctx)
+
+	var output []byte
+	err := runOperation(ctx, r.logger(), r.Run, OperationSnapshots, func(ctx context.Context) error {
+		var err error
+		output, err = r.execOutput(ctx, append(r.commandArgs("snapshots"), "--json"))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s repository snapshot list: %w", r.Backend.Type, err)
+	}
+
+	snapshots := []Snapshot{}
+	if err := json.Unmarshal(output, &snapshots); err != nil {
+		return nil, fmt.Errorf("%s repository snapshot list: decode: %w", r.Backend.Type, err)
+	}
+
+	return snapshots, nil
+}
+
+// Ls returns nodes of snapshot selected by opts
+func (r BackupRepository) Ls(ctx context.Context, snapshot string, opts LsOptions) ([]Node, error) {
+	if err := r.preflight(); err != nil {
+		return nil, fmt.Errorf("%s repository ls: %w", r.Backend.Type, err)
+	}
+	r.unlockStale(ctx)
+
+	commandArg := append(r.commandArgs("ls"), snapshot)
+	if opts.Path != "" {
+		commandArg = append(commandArg, opts.Path)
+	}
+	if opts.Recursive {
+		commandArg = append(commandArg, "--recursive")
+	}
+
+	var nodes []Node
+	err := runOperation(ctx, r.logger(), r.Run, OperationLs, func(ctx context.Context) error {
+		nodes = []Node{}
+		return r.execJSON(ctx, commandArg, func(line string) {
+			var message struct {
+				StructType  string `json:"struct_type"`
+				MessageType string `json:"message_type"`
+				Node
+			}
+			if err := json.Unmarshal([]byte(line), &message); err != nil {
+				r.logger().Debug("skip restic ls output", "line", line)
+				return
+			}
+			if message.StructType == "node" || message.MessageType == "node" {
+				nodes = append(nodes, message.Node)
+			}
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s repository ls: %w", r.Backend.Type, err)
+	}
+
+	return nodes, nil
+}
+
+// Find returns nodes matching pattern in all snapshots, narrowed by opts
+func (r BackupRepository) Find(ctx context.Context, pattern string, opts FindOptions) ([]FindMatch, error) {
+	if err := r.preflight(); err != nil {
+		return nil, fmt.Errorf("%s repository find: %w", r.Backend.Type, err)
+	}
+	r.unlockStale(ctx)
+
+	commandArg := append(r.commandArgs("find"), pattern, "--json")
+	if opts.Oldest != "" {
+		commandArg = append(commandArg, "--oldest", opts.Oldest)
+	}
+	if opts.Newest != "" {
+		commandArg = append(commandArg, "--newest", opts.Newest)
+	}
+	for _, path := range opts.Paths {
+		commandArg = append(commandArg, "--path", path)
+	}
+
+	var output []byte
+	err := runOperation(ctx, r.logger(), r.Run, OperationFind, func(ctx context.Context) error {
+		var err error
+		output, err = r.execOutput(ctx, commandArg)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s repository find: %w", r.Backend.Type, err)
+	}
+
+	var results []struct {
+		Snapshot string `json:"snapshot"`
+		Matches  []Node `json:"matches"`
+	}
+	if len(bytes.TrimSpace(output)) == 0 {
+		return []FindMatch{}, nil
+	}
+	if err := json.Unmarshal(output, &results); err != nil {
+		return nil, fmt.Errorf("%s repository find: decode: %w", r.Backend.Type, err)
+	}
+
+	matches := []FindMatch{}
+	for _, result := range results {
+		for _, node := range result.Matches {
+			matches = append(matches, FindMatch{Snapshot: result.Snapshot, Node: node})
+		}
+	}
+
+	return matches, nil
+}
+
+// NeedsResolve reports whether snapshot ref is relative to latest snapshot,
+// and is resolved to snapshot ID by ResolveSnapshot
+func NeedsResolve(ref string) bool {
+	return ref == latestSnapshot || strings.HasPrefix(ref, latestSnapshot+"~")
+}
+
+// ResolveSnapshot returns ID of snapshot referenced by ref in snapshots ordered
+// from oldest to newest. ref is "latest", "latest~N" for Nth snapshot before
+// latest, or snapshot ID which is returned as is
+func ResolveSnapshot(snapshots []Snapshot, ref string) (string, error) {
+	if !NeedsResolve(ref) {
+		return ref, nil
+	}
+
+	back := 0
+	if ref != latestSnapshot {
+		n, err := strconv.Atoi(strings.TrimPrefix(ref, latestSnapshot+"~"))
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("resolve snapshot %s: invalid reference", ref)
+		}
+		back = n
+	}
+	if back >= len(snapshots) {
+		return "", fmt.Errorf("resolve snapshot %s: %w, %d snapshots in repository", ref, ErrSnapshotNotFound, len(snapshots))
+	}
+
+	return snapshots[len(snapshots)-1-back].ID, nil
+}
@@ -0,0 +1,145 @@
+package restic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Modes of StatsOptions, as restic stats "--mode" option
+const (
+	StatsRestoreSize     string = "restore-size"
+	StatsRawData         string = "raw-data"
+	StatsFilesByContents string = "files-by-contents"
+)
+
+// Stats is repository size listed by restic stats --json
+type Stats struct {
+	TotalSize              uint64  `json:"total_size"`
+	TotalUncompressedSize  uint64  `json:"total_uncompressed_size,omitempty"`
+	CompressionRatio       float64 `json:"compression_ratio,omitempty"`
+	CompressionProgress    float64 `json:"compression_progress,omitempty"`
+	CompressionSpaceSaving float64 `json:"compression_space_saving,omitempty"`
+	TotalFileCount         uint64  `json:"total_file_count,omitempty"`
+	TotalBlobCount         uint64  `json:"total_blob_count,omitempty"`
+	SnapshotsCount         uint64  `json:"snapshots_count"`
+}
+
+// StatsOptions selects how and which snapshots Stats counts
+type StatsOptions struct {
+	// Mode is StatsRestoreSize, StatsRawData or StatsFilesByContents,
+	// StatsRestoreSize if empty
+	Mode string
+	// Snapshots are counted, all snapshots if empty
+	Snapshots []string
+}
+
+// DailyGrowth is growth of repository by snapshots taken on Date
+type DailyGrowth struct {
+	// Date is local date of snapshots, as "2006-01-02"
+	Date      string `json:"date"`
+	Snapshots int    `json:"snapshots"`
+	// DataAdded and DataAddedPacked are new data of summarized snapshots,
+	// before and after compression
+	DataAdded       uint64 `json:"data_added"`
+	DataAddedPacked uint64 `json:"data_added_packed"`
+	// RestoreSize is restore size of last snapshot of day
+	RestoreSize uint64 `json:"restore_size"`
+	// Unsummarized counts snapshots without summary, taken by restic before
+	// 0.17, whose added data is not counted
+	Unsummarized int `json:"unsummarized"`
+}
+
+// Statter is implemented by repositories reporting their size
+type Statter interface {
+	Stats(ctx context.Context, opts StatsOptions) (Stats, error)
+	StatsTrend(ctx context.Context) ([]DailyGrowth, error)
+}
+
+// Validate checks that Mode is a known stats mode
+func (o StatsOptions) Validate() error {
+	switch o.Mode {
+	case "", StatsRestoreSize, StatsRawData, StatsFilesByContents:
+		return nil
+	}
+
+	return fmt.Errorf("stats: mode should be %s, %s or %s: %s", StatsRestoreSize, StatsRawData, StatsFilesByContents, o.Mode)
+}
+
+// Stats returns size of snapshots selected by opts
+func (r BackupRepository) Stats(ctx context.Context, opts StatsOptions) (Stats, error) {
+	if err := opts.Validate(); err != nil {
+		return Stats{}, fmt.Errorf("%s repository stats: %w", r.Backend.Type, err)
+	}
+	if err := r.preflight(); err != nil {
+		return Stats{}, fmt.Errorf("%s repository stats: %w", r.Backend.Type, err)
+	}
+	r.unlockStale(ctx)
+
+	stats, err := r.stats(ctx, opts)
+	if err != nil {
+		return Stats{}, fmt.Errorf("%s repository stats: %w", r.Backend.Type, err)
+	}
+
+	return stats, nil
+}
+
+// StatsTrend returns growth of repository by day from snapshot summaries,
+// ordered from oldest to newest day
+func (r BackupRepository) StatsTrend(ctx context.Context) ([]DailyGrowth, error) {
+	snapshots, err := r.SnapshotList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s repository stats trend: %w", r.Backend.Type, err)
+	}
+
+	days := []DailyGrowth{}
+	for _, snapshot := range snapshots {
+		date := snapshot.Time.Local().Format(time.DateOnly)
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			days = append(days, DailyGrowth{Date: date})
+		}
+		day := &days[len(days)-1]
+		day.Snapshots++
+
+		if snapshot.Summary == nil {
+			day.Unsummarized++
+			stats, err := r.stats(ctx, StatsOptions{Mode: StatsRestoreSize, Snapshots: []string{snapshot.ID}})
+			if err != nil {
+				return nil, fmt.Errorf("%s repository stats trend: %w", r.Backend.Type, err)
+			}
+			day.RestoreSize = stats.TotalSize
+			continue
+		}
+		day.DataAdded += snapshot.Summary.DataAdded
+		day.DataAddedPacked += snapshot.Summary.DataAddedPacked
+		day.RestoreSize = snapshot.Summary.TotalBytesProcessed
+	}
+
+	return days, nil
+}
+
+func (r BackupRepository) stats(ctx context.Context, opts StatsOptions) (Stats, error) {
+	commandArg := append(r.commandArgs("stats"), "--json")
+	if opts.Mode != "" {
+		commandArg = append(commandArg, "--mode", opts.Mode)
+	}
+	commandArg = append(commandArg, opts.Snapshots...)
+
+	var output []byte
+	err := runOperation(ctx, r.logger(), r.Run, OperationStats, func(ctx context.Context) error {
+		var err error
+		output, err = r.execOutput(ctx, commandArg)
+		return err
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+
+	var stats Stats
+	if err := json.Unmarshal(output, &stats); err != nil {
+		return Stats{}, fmt.Errorf("decode: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,149 @@
+package restic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TagOptions selects snapshots and changes of their tags
+type TagOptions struct {
+	// Snapshots are IDs of changed snapshots, all snapshots if empty
+	Snapshots []string
+	// Add and Remove tags of snapshots, or Set replaces all their tags
+	Add    []string
+	Remove []string
+	Set    []string
+}
+
+// TagChange is tags of Snapshot before and after change
+type TagChange struct {
+	Snapshot string
+	Before   []string
+	After    []string
+}
+
+// Tagger is implemented by repositories changing snapshot tags. Without apply,
+// changes are returned without changing snapshots
+type Tagger interface {
+	Tag(ctx context.Context, opts TagOptions, apply bool) ([]TagChange, error)
+}
+
+// Validate checks that tags are either set, or added and removed
+func (o TagOptions) Validate() error {
+	if len(o.Add) == 0 && len(o.Remove) == 0 && len(o.Set) == 0 {
+		return errors.New("tag: no tags to add, remove or set")
+	}
+	if len(o.Set) > 0 && (len(o.Add) > 0 || len(o.Remove) > 0) {
+		return errors.New("tag: set should not be combined with add or remove")
+	}
+
+	return nil
+}
+
+// tags returns tags changed from tags by options
+func (o TagOptions) tags(tags []string) []string {
+	if len(o.Set) > 0 {
+		tags = o.Set
+	}
+
+	result := []string{}
+	for _, tag := range append(slices.Clip(tags), o.Add...) {
+		if !slices.Contains(o.Remove, tag) && !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
+
+// Tag changes tags of snapshots by opts if apply is set, returning changes of
+// snapshots whose tags differ. Changed snapshots are saved with new IDs
+func (r BackupRepository) Tag(ctx context.Context, opts TagOptions, apply bool) ([]TagChange, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("%s repository tag: %w", r.Backend.Type, err)
+	}
+	if err := r.preflight(); err != nil {
+		return nil, fmt.Errorf("%s repository tag: %w", r.Backend.Type, err)
+	}
+	r.unlockStale(ctx)
+
+	snapshots, err := r.SnapshotList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s repository tag: %w", r.Backend.Type, err)
+	}
+	selected, err := selectSnapshots(snapshots, opts.Snapshots)
+	if err != nil {
+		return nil, fmt.Errorf("%s repository tag: %w", r.Backend.Type, err)
+	}
+
+	changes := []TagChange{}
+	for _, snapshot := range selected {
+		before := snapshot.Tags
+		if before == nil {
+			before = []string{}
+		}
+		after := opts.tags(before)
+		if !slices.Equal(before, after) {
+			changes = append(changes, TagChange{Snapshot: snapshot.ID, Before: before, After: after})
+		}
+	}
+	if !apply || len(changes) == 0 {
+		return changes, nil
+	}
+
+	commandArg := r.commandArgs("tag")
+	for _, tag := range opts.Add {
+		commandArg = append(commandArg, "--add", tag)
+	}
+	for _, tag := range opts.Remove {
+		commandArg = append(commandArg, "--remove", tag)
+	}
+	for _, tag := range opts.Set {
+		commandArg = append(commandArg, "--set", tag)
+	}
+	for _, change := range changes {
+		commandArg = append(commandArg, change.Snapshot)
+	}
+
+	// Only failures to lock repository are retried, other failures may come
+	// after some snapshots were changed
+	r.logger().Info("restic tag", "snapshots", len(changes))
+	err = runOperation(ctx, r.logger(), r.Run, OperationTag, func(ctx context.Context) error {
+		_, err := r.execOutput(ctx, commandArg)
+		if err != nil && failureKind(err).Transient() && failureKind(err) != FailureLocked {
+			return fmt.Errorf("%w, snapshots may be partially changed: %w", errNoRetry, err)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s repository tag: %w", r.Backend.Type, err)
+	}
+
+	return changes, nil
+}
+
+// selectSnapshots returns snapshots whose IDs start with one of ids, all
+// snapshots if ids is empty
+func selectSnapshots(snapshots []Snapshot, ids []string) ([]Snapshot, error) {
+	if len(ids) == 0 {
+		return snapshots, nil
+	}
+
+	selected := []Snapshot{}
+	for _, id := range ids {
+		index := slices.IndexFunc(snapshots, func(snapshot Snapshot) bool {
+			return strings.HasPrefix(snapshot.ID, id)
+		})
+		if index < 0 {
+			return nil, fmt.Errorf("snapshot %s: %w", id, ErrSnapshotNotFound)
+		}
+		if !slices.ContainsFunc(selected, func(snapshot Snapshot) bool { return snapshot.ID == snapshots[index].ID }) {
+			selected = append(selected, snapshots[index])
+		}
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,39 @@
+package restic_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/liuminhaw/wrestic-bkp/restic"
+	"github.com/liuminhaw/wrestic-bkp/restic/resticfake"
+)
+
+func TestTagRetry(t *testing.T) {
+	lockedFailure := resticfake.Response{ExitCode: 11, Stderr: "repository is already locked"}
+	networkFailure := resticfake.Response{ExitCode: 1, Stderr: "Fatal: read tcp: connection reset by peer"}
+
+	tests := []struct {
+		name      string
+		responses []resticfake.Response
+		wantCalls int
+		wantErr   bool
+	}{
+		{"locked retried", []resticfake.Response{fakeResponses["snapshots"], lockedFailure, {}}, 3, false},
+		{"network not retried", []resticfake.Response{fakeResponses["snapshots"], networkFailure, {}}, 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := resticfake.New(tt.responses...)
+			repo := fakeRepository(fake, restic.RunSettings{Retry: fastRetry})
+
+			_, err := repo.Tag(context.Background(), restic.TagOptions{Add: []string{"keep"}}, true)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Tag() error = %v, want error %t", err, tt.wantErr)
+			}
+			if calls := len(fake.Calls()); calls != tt.wantCalls {
+				t.Errorf("restic called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -24,10 +24,14 @@ type ResticTest interface {
 	TestClean() error
 }
 
+// LocalRepositoryTest sets up local repository test environment, password
+// settings are those of repository section in config, at most one is set
 type LocalRepositoryTest struct {
-	Password    string
-	Destination string
-	Sources     []string
+	Password        string
+	PasswordFile    string
+	PasswordCommand string
+	Destination     string
+	Sources         []string
 }
 
 func (t LocalRepositoryTest) TestGenerate() error {
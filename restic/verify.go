@@ -0,0 +1,309 @@
+package restic
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AfterBackup value of VerifySettings, besides CheckNever
+const VerifyMonthly string = "monthly"
+
+// Status values of VerifyFile
+const (
+	VerifyOK            string = "ok"
+	VerifyNotInSnapshot string = "not in snapshot"
+	VerifyMissing       string = "not restored"
+	VerifySizeMismatch  string = "size mismatch"
+)
+
+// Source values of VerifyFile compared with live source file
+const (
+	SourceMatch   string = "match"
+	SourceDiffers string = "differs"
+	// SourceChanged is source file modified since snapshot, not compared
+	SourceChanged string = "changed"
+	SourceMissing string = "missing"
+)
+
+const (
+	defaultVerifyFiles int    = 10
+	verifyStateSuffix  string = ".verify"
+)
+
+var ErrVerifyFailed = errors.New("restored files failed verification")
+
+// VerifySettings controls restore drill of a backup
+type VerifySettings struct {
+	// AfterBackup is CheckNever or VerifyMonthly, CheckNever if empty
+	AfterBackup string `yaml:"afterBackup,omitempty"`
+	// Files is number of random files restored, 10 if not set
+	Files int `yaml:"files,omitempty"`
+	// Paths are critical files or directories restored instead of random files
+	Paths []string `yaml:"paths,omitempty"`
+	// CompareSource compares restored files with source files unchanged since snapshot
+	CompareSource bool `yaml:"compareSource,omitempty"`
+}
+
+// VerifyOptions are options of a single restore drill
+type VerifyOptions struct {
+	// Snapshot is ID of restored snapshot, latest snapshot if empty
+	Snapshot      string
+	Files         int
+	Paths         []string
+	CompareSource bool
+}
+
+// VerifyFile is verification result of a restored file
+type VerifyFile struct {
+	Path   string
+	Size   uint64
+	Status string
+	// Source is SourceMatch, SourceDiffers, SourceChanged or SourceMissing
+	// if compared with source file, empty otherwise
+	Source string
+}
+
+// VerifyResult is result of restore drill of Snapshot
+type VerifyResult struct {
+	Snapshot string
+	Files    []VerifyFile
+}
+
+// Verifier is implemented by repositories running restore drills
+type Verifier interface {
+	Verify(ctx context.Context, opts VerifyOptions) (VerifyResult, error)
+}
+
+// Validate checks that AfterBackup and Files values are valid
+func (s VerifySettings) Validate() error {
+	switch s.AfterBackup {
+	case "", CheckNever, VerifyMonthly:
+	default:
+		return fmt.Errorf("verify: afterBackup should be %s or %s: %s", CheckNever, VerifyMonthly, s.AfterBackup)
+	}
+	if s.Files < 0 {
+		return fmt.Errorf("verify: files should not be negative: %d", s.Files)
+	}
+
+	return nil
+}
+
+// Due reports whether restore drill should run after backup at now, given
+// time of last drill. Monthly drill is due an hour early, so that backup
+// scheduled at same time is not delayed a day by duration of the last drill
+func (s VerifySettings) Due(lastVerify, now time.Time) bool {
+	if s.AfterBackup != VerifyMonthly {
+		return false
+	}
+
+	return lastVerify.IsZero() || !now.Before(lastVerify.AddDate(0, 1, 0).Add(-time.Hour))
+}
+
+// Options returns restore drill options of settings
+func (s VerifySettings) Options() VerifyOptions {
+	return VerifyOptions{Files: s.Files, Paths: s.Paths, CompareSource: s.CompareSource}
+}
+
+// Failed returns number of files failing verification
+func (r VerifyResult) Failed() int {
+	failed := 0
+	for _, file := range r.Files {
+		if file.Status != VerifyOK || file.Source == SourceDiffers {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// Verify restores sample of files from snapshot into a temporary directory,
+// letting restic verify their contents against blob hashes of repository,
+// then checks restored sizes and optionally compares them with unchanged
+// source files. Temporary directory is removed afterwards
+func (r BackupRepository) Verify(ctx context.Context, opts VerifyOptions) (VerifyResult, error) {
+	snapshot := opts.Snapshot
+	if snapshot == "" || NeedsResolve(snapshot) {
+		ref := snapshot
+		if ref == "" {
+			ref = latestSnapshot
+		}
+		snapshots, err := r.SnapshotList(ctx)
+		if err != nil {
+			return VerifyResult{}, fmt.Errorf("%s repository verify: %w", r.Backend.Type, err)
+		}
+		if snapshot, err = ResolveSnapshot(snapshots, ref); err != nil {
+			return VerifyResult{}, fmt.Errorf("%s repository verify: %w", r.Backend.Type, err)
+		}
+	}
+
+	nodes, err := r.Ls(ctx, snapshot, LsOptions{})
+	if err != nil {
+		return VerifyResult{}, fmt.Errorf("%s repository verify: %w", r.Backend.Type, err)
+	}
+	result := VerifyResult{Snapshot: snapshot}
+	files := sampleFiles(nodes, opts, &result)
+	if len(files) == 0 {
+		return result, nil
+	}
+
+	target, err := os.MkdirTemp("", "wrestic-bkp-verify-*")
+	if err != nil {
+		return VerifyResult{}, fmt.Errorf("%s repository verify: %w", r.Backend.Type, err)
+	}
+	defer os.RemoveAll(target)
+
+	commandArg := append(r.commandArgs("restore"), snapshot, "--target", target, "--verify")
+	for _, file := range files {
+		commandArg = append(commandArg, "--include", escapePattern(file.Path))
+	}
+	err = runOperation(ctx, r.logger(), r.Run, OperationVerify, func(ctx context.Context) error {
+		return r.execLines(ctx, "restore", commandArg, func(line string) {
+			r.logger().Debug("restic restore", "output", line)
+		})
+	})
+	if err != nil {
+		return VerifyResult{}, fmt.Errorf("%s repository verify: %w", r.Backend.Type, err)
+	}
+
+	for _, file := range files {
+		result.Files = append(result.Files, verifyFile(target, file, opts.CompareSource))
+	}
+
+	return result, nil
+}
+
+// sampleFiles returns files of nodes under opts.Paths, or opts.Files random
+// files if no path is given. Paths without files are added to result
+func sampleFiles(nodes []Node, opts VerifyOptions, result *VerifyResult) []Node {
+	files := []Node{}
+	if len(opts.Paths) == 0 {
+		for _, node := range nodes {
+			if node.Type == "file" {
+				files = append(files, node)
+			}
+		}
+		count := opts.Files
+		if count == 0 {
+			count = defaultVerifyFiles
+		}
+		rand.Shuffle(len(files), func(i, j int) { files[i], files[j] = files[j], files[i] })
+		if len(files) > count {
+			files = files[:count]
+		}
+		return files
+	}
+
+	for _, path := range opts.Paths {
+		found := false
+		for _, node := range nodes {
+			if hasPathPrefix(node.Path, path) {
+				found = true
+				if node.Type == "file" {
+					files = append(files, node)
+				}
+			}
+		}
+		if !found {
+			result.Files = append(result.Files, VerifyFile{Path: path, Status: VerifyNotInSnapshot})
+		}
+	}
+
+	return files
+}
+
+// verifyFile checks file restored in target against its snapshot node,
+// and against source file if compareSource is set and source is unchanged
+func verifyFile(target string, node Node, compareSource bool) VerifyFile {
+	file := VerifyFile{Path: node.Path, Size: node.Size, Status: VerifyOK}
+	restored := filepath.Join(target, node.Path)
+	info, err := os.Stat(restored)
+	switch {
+	case err != nil:
+		file.Status = VerifyMissing
+		return file
+	case uint64(info.Size()) != node.Size:
+		file.Status = VerifySizeMismatch
+		return file
+	}
+	if !compareSource {
+		return file
+	}
+
+	source, err := os.Stat(node.Path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		file.Source = SourceMissing
+		return file
+	case err != nil || uint64(source.Size()) != node.Size || !source.ModTime().Equal(node.ModTime):
+		file.Source = SourceChanged
+		return file
+	}
+
+	restoredHash, err := fileHash(restored)
+	if err != nil {
+		file.Status = VerifyMissing
+		return file
+	}
+	sourceHash, err := fileHash(node.Path)
+	if err != nil {
+		file.Source = SourceChanged
+		return file
+	}
+	file.Source = SourceMatch
+	if restoredHash != sourceHash {
+		file.Source = SourceDiffers
+	}
+
+	return file
+}
+
+// fileHash returns sha256 hash of file content at path
+func fileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// escapePattern escapes characters of path matched as pattern by restic
+func escapePattern(path string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`)
+	return replacer.Replace(path)
+}
+
+// LastVerify returns time of last successful restore drill of backup recorded
+// in dir, zero time if backup was never verified
+func LastVerify(dir, backup string) (time.Time, error) {
+	verified, err := readStateTime(statePath(dir, backup, verifyStateSuffix))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("last verify: %w", err)
+	}
+
+	return verified, nil
+}
+
+// RecordVerify records verified as time of last successful restore drill of backup in dir
+func RecordVerify(dir, backup string, verified time.Time) error {
+	if err := writeStateTime(dir, statePath(dir, backup, verifyStateSuffix), verified); err != nil {
+		return fmt.Errorf("record verify: %w", err)
+	}
+
+	return nil
+}
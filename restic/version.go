@@ -0,0 +1,106 @@
+package restic
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Features requiring newer restic than the oldest supported one
+const (
+	FeatureCopyFromRepo   string = "copy --from-repo"
+	FeatureRewrite        string = "rewrite"
+	FeatureRepairIndex    string = "repair index"
+	FeatureRepairSnapshot string = "repair snapshots"
+	FeatureRepairPacks    string = "repair packs"
+)
+
+// featureVersions maps feature to minimum restic version supporting it
+var featureVersions = map[string]Version{
+	FeatureCopyFromRepo:   {0, 14, 0},
+	FeatureRewrite:        {0, 15, 0},
+	FeatureRepairIndex:    {0, 16, 0},
+	FeatureRepairSnapshot: {0, 16, 0},
+	FeatureRepairPacks:    {0, 16, 0},
+}
+
+var versionPattern = regexp.MustCompile(`restic (\d+)\.(\d+)\.(\d+)`)
+
+// Version is semantic version of restic program
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether v is same or newer than min
+func (v Version) AtLeast(min Version) bool {
+	if v.Major != min.Major {
+		return v.Major > min.Major
+	}
+	if v.Minor != min.Minor {
+		return v.Minor > min.Minor
+	}
+
+	return v.Patch >= min.Patch
+}
+
+// ParseVersion reads version from 'restic version' output,
+// e.g. "restic 0.16.4 compiled with go1.21.6 on linux/amd64"
+func ParseVersion(output string) (Version, error) {
+	matches := versionPattern.FindStringSubmatch(output)
+	if matches == nil {
+		return Version{}, fmt.Errorf("parse version: unexpected output %q", output)
+	}
+
+	var version Version
+	for i, field := range []*int{&version.Major, &version.Minor, &version.Patch} {
+		number, err := strconv.Atoi(matches[i+1])
+		if err != nil {
+			return Version{}, fmt.Errorf("parse version: %w", err)
+		}
+		*field = number
+	}
+
+	return version, nil
+}
+
+// ResticVersion runs 'restic version' through runner and returns the parsed version
+func ResticVersion(ctx context.Context, runner Runner) (Version, error) {
+	var stdout, stderr bytes.Buffer
+	err := runner.Run(ctx, Command{
+		Args:   []string{"version"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return Version{}, commandError(ctx, "restic version", stderr.Bytes(), err)
+	}
+
+	return ParseVersion(stdout.String())
+}
+
+// RequireFeature returns error naming the required version if restic run by
+// runner is older than the version supporting feature
+func RequireFeature(ctx context.Context, runner Runner, feature string) error {
+	min, ok := featureVersions[feature]
+	if !ok {
+		return nil
+	}
+
+	version, err := ResticVersion(ctx, runner)
+	if err != nil {
+		return fmt.Errorf("require %s: %w", feature, err)
+	}
+	if !version.AtLeast(min) {
+		return fmt.Errorf("%s requires restic >= %s, found %s", feature, min, version)
+	}
+
+	return nil
+}